@@ -18,6 +18,8 @@ type Argument interface {
 	Description() string
 
 	Optional() bool
+
+	Default() any
 }
 
 var _ Argument = (*argument)(nil)
@@ -29,6 +31,7 @@ type argument struct {
 	description string
 	ttype       ytype
 	value       any
+	defvalue    any
 	optional    bool
 	cvalidators []func(Argument) error
 }
@@ -48,6 +51,12 @@ func NewArgument(
 		opt(a)
 	}
 
+	if a.defvalue != nil {
+		if _, err := checkDefault(a.ttype, false, a.defvalue); err != nil {
+			panic(fmt.Errorf("invalid argument: argument '%s' has %w", a.name, err))
+		}
+	}
+
 	return a
 }
 
@@ -57,6 +66,15 @@ func WithArgumentOptional(optional bool) argumentOption {
 	}
 }
 
+// WithArgumentDefault sets the value of the argument which is used when the argument is not passed.
+// The value must be of the Go type of the argument type, e.g. int for Integer.
+// An argument with a default value is optional.
+func WithArgumentDefault(v any) argumentOption {
+	return func(a *argument) {
+		a.defvalue = v
+	}
+}
+
 // WithArgumentValidator is an argumentOption function that allows adding custom validators to an argument.
 // It takes a function with an Argument parameter that returns an error, and appends it to the argument's
 // list of custom validators.
@@ -86,7 +104,11 @@ func (a *argument) Description() string {
 }
 
 func (a *argument) Optional() bool {
-	return a.optional
+	return a.optional || a.defvalue != nil
+}
+
+func (a *argument) Default() any {
+	return a.defvalue
 }
 
 func (a *argument) String() string {
@@ -95,17 +117,23 @@ func (a *argument) String() string {
 
 // validate validates the argument value against its type and custom validators.
 // It returns an error if the validation fails.
+// If the argument is not passed, its default value is used.
 func (a *argument) validate() error {
-	if a.value == nil {
+	switch {
+	case a.value != nil:
+		v, err := vfuncs[a.ttype](a.value)
+		if err != nil {
+			return err
+		}
+		a.value = v
+	case a.defvalue != nil:
+		a.value = a.defvalue
+	case a.optional:
+		return nil
+	default:
 		return fmt.Errorf("invalid argument: missing value for argument '%s'", a.name)
 	}
 
-	v, err := vfuncs[a.ttype](a.value)
-	if err != nil {
-		return err
-	}
-	a.value = v
-
 	for _, cvalidator := range a.cvalidators {
 		if err := cvalidator(a); err != nil {
 			return err
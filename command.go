@@ -1,9 +1,11 @@
 package yacli
 
 import (
+	stdcontext "context"
 	"fmt"
 	"os"
 	"strings"
+	"unicode"
 )
 
 type commandOption func(*command)
@@ -17,13 +19,20 @@ type Command interface {
 	// Description returns a brief description of the command.
 	Description() string
 
+	// Aliases returns the alternative names of the command.
+	Aliases() []string
+
 	// Deprecated returns a boolean indicating whether or not the command is deprecated.
 	Deprecated() bool
 
+	// Hidden returns a boolean indicating whether or not the command is hidden from the help message.
+	Hidden() bool
+
 	// Subcommands returns a list of subcommands that are associated with this command.
 	Subcommands() []Command
 
 	// Flags returns a list of flags of command.
+	// Hidden subcommands and flags are not listed by Subcommands and Flags.
 	Flags() []Flag
 
 	// Arguments returns a list of arguments that were passed to command.
@@ -47,16 +56,35 @@ type command struct {
 	// description is a brief description of the command.
 	description string
 
+	// aliases is the alternative names the command can be invoked by.
+	aliases []string
+
 	// deprecated is a flag that indicates if the command is deprecated or not
 	deprecated bool
 
+	// message is the deprecation message printed when the deprecated command is used.
+	message string
+
+	// replacement is the path of the command, starting from the root command,
+	// which the deprecated command is forwarded to.
+	replacement string
+
+	// hidden is a flag that indicates if the command is hidden from the help message or not.
+	hidden bool
+
 	// cs is the sub-commands under this command.
 	cs commandset
 
-	// fsl is the flags associated with this command.
+	// parent is the command this command is a subcommand of.
+	parent *command
+
+	// fs is the flags associated with this command in the order they were defined.
+	fs []*flag
+
+	// fsl is the flags associated with this command by their long names and aliases.
 	fsl flagset
 
-	// fss is the flags associated with this command.
+	// fss is the flags associated with this command by their short names and aliases.
 	fss flagset
 
 	// fg is the flag groups associated with this command.
@@ -67,6 +95,23 @@ type command struct {
 
 	// action is the function to execute when the command is invoked.
 	action func(Context) error
+
+	// mode is the parsing mode used when the command is run as a root command.
+	mode pmode
+
+	// envPrefix is the prefix of the environment variables derived for every flag
+	// of the command tree when the command is a root command.
+	envPrefix string
+
+	// config is the configuration file settings used when the command is a root command.
+	config *config
+
+	// prefix enables matching long flags and subcommands by an unambiguous prefix
+	// when the command is run as a root command.
+	prefix bool
+
+	// responseFiles enables expansion of '@file' arguments when the command is run as a root command.
+	responseFiles bool
 }
 
 // NewRootCommand returns a new instance of a `command` struct with the name of the command
@@ -83,15 +128,24 @@ func NewRootCommand(opts ...commandOption) *command {
 // It then applies the given options to the command.
 // Returns a pointer to the created command.
 func NewCommand(name string, opts ...commandOption) *command {
-	hFlag := &flag{name: "help", short: "h", description: "Print this message", ttype: Bool}
+	hFlag := &flag{name: "help", short: "h", description: "Print this message", ttype: Bool, noAutoEnv: true}
+	haFlag := &flag{
+		name:        "help-all",
+		description: "Print this message including hidden flags and subcommands",
+		ttype:       Bool,
+		hidden:      true,
+		noAutoEnv:   true,
+	}
 
 	c := &command{
 		name: name,
-		fsl:  flagset{"help": hFlag},
-		fss:  flagset{"h": hFlag},
+		fsl:  make(flagset),
+		fss:  make(flagset),
 		fg:   make(flaggroup),
 		cs:   make(commandset),
 	}
+	c.addFlag(hFlag)
+	c.addFlag(haFlag)
 
 	for _, opt := range opts {
 		opt(c)
@@ -102,17 +156,28 @@ func NewCommand(name string, opts ...commandOption) *command {
 
 // WithSubcommand returns a commandOption that adds a subcommand to a command.
 //
-// The subcommand is added to the parent command's commandset using the subcommand's name as the key.
-// The subcommand can be invoked by calling the parent command with the subcommand's name as an argument.
+// The subcommand is added to the parent command's commandset using the subcommand's name
+// and aliases as the keys. The subcommand can be invoked by calling the parent command
+// with the subcommand's name or any of its aliases as an argument.
 func WithSubcommand(subc *command) commandOption {
 	return func(c *command) {
-		if _, ok := c.cs.get(subc.name); ok {
-			panic(fmt.Errorf(
-				"invalid command: subcommand with name '%s' is already defined on command '%s'",
-				subc.name, c.name,
-			))
+		for _, name := range append([]string{subc.name}, subc.aliases...) {
+			if !c.cs.set(name, subc) {
+				panic(fmt.Errorf(
+					"invalid command: subcommand with name '%s' is already defined on command '%s'",
+					name, c.name,
+				))
+			}
 		}
-		c.cs.set(subc.name, subc)
+		subc.parent = c
+	}
+}
+
+// WithCommandAliases sets the alternative names the command can be invoked by, e.g. 'rm' for 'remove'.
+// The aliases are registered in the parent command when the command is added with WithSubcommand.
+func WithCommandAliases(aliases ...string) commandOption {
+	return func(c *command) {
+		c.aliases = append(c.aliases, aliases...)
 	}
 }
 
@@ -132,6 +197,77 @@ func WithCommandDeprecated(d bool) commandOption {
 	}
 }
 
+// WithCommandDeprecationMessage marks the command as deprecated with the given message,
+// which is printed to stderr when the command is used.
+func WithCommandDeprecationMessage(msg string) commandOption {
+	return func(c *command) {
+		c.deprecated = true
+		c.message = msg
+	}
+}
+
+// WithCommandReplacement marks the command as deprecated in favor of the command with the given path,
+// e.g. 'cluster deploy'. The path starts from the first subcommand of the root command.
+// The deprecated command is forwarded to the replacement: the rest of the command line
+// is parsed and executed by the replacement.
+func WithCommandReplacement(path string) commandOption {
+	return func(c *command) {
+		c.deprecated = true
+		c.replacement = path
+	}
+}
+
+// WithCommandHidden hides the command from the usage and help messages of its parent,
+// the command can still be invoked.
+// Hidden commands are shown by the '--help-all' flag.
+func WithCommandHidden() commandOption {
+	return func(c *command) {
+		c.hidden = true
+	}
+}
+
+// WithParseMode sets the parsing mode of the command line.
+//
+// By default flags can be interleaved with positional arguments (ParsePermute).
+// ParsePOSIX requires all flags to be passed before positional arguments.
+// The option takes effect only on the root command.
+func WithParseMode(m pmode) commandOption {
+	return func(c *command) {
+		c.mode = m
+	}
+}
+
+// WithResponseFiles enables expansion of response files.
+//
+// Every '@path' argument is replaced with the words read from the file at path
+// before the command line is parsed. '@@literal' is passed as '@literal'.
+// The option takes effect only on the root command.
+func WithResponseFiles(e bool) commandOption {
+	return func(c *command) {
+		c.responseFiles = e
+	}
+}
+
+// WithPrefixMatching enables referring to long flags and subcommands by an unambiguous prefix
+// of their names, e.g. '--verb' for '--verbose'. An exact match always takes precedence.
+// The option takes effect only on the root command.
+func WithPrefixMatching(e bool) commandOption {
+	return func(c *command) {
+		c.prefix = e
+	}
+}
+
+// WithEnvPrefix binds every flag of the command tree to the environment variable
+// derived from the prefix, the subcommand names and the flag name,
+// e.g. 'MYAPP_DEPLOY_DRY_RUN' for 'myapp deploy --dry-run'.
+// Variables bound with WithFlagEnv take precedence over the derived ones.
+// The option takes effect only on the root command.
+func WithEnvPrefix(prefix string) commandOption {
+	return func(c *command) {
+		c.envPrefix = prefix
+	}
+}
+
 // WithFlags sets the provided flags as options for the command.
 //
 // This commandOption takes a variable number of flag pointers as input
@@ -141,9 +277,22 @@ func WithFlags(flags ...*flag) commandOption {
 	return func(c *command) {
 		g := c.fg.new(groupDefault)
 		for _, f := range flags {
-			panicIfFlagAlreadyDefined(c, f)
-			c.fsl.set(f.name, f)
-			c.fss.set(f.short, f)
+			c.addFlag(f)
+			g.add(f)
+		}
+	}
+}
+
+// WithPersistentFlags sets the provided flags as options for the command and all its descendants.
+//
+// Persistent flags are accepted by every subcommand, their values can be read from the subcommand's
+// Context. A flag of a subcommand with the same name shadows the persistent flag.
+func WithPersistentFlags(flags ...*flag) commandOption {
+	return func(c *command) {
+		g := c.fg.new(groupDefault)
+		for _, f := range flags {
+			f.persistent = true
+			c.addFlag(f)
 			g.add(f)
 		}
 	}
@@ -159,11 +308,9 @@ func WithMutualExclusiveFlags(flags ...*flag) commandOption {
 	return func(c *command) {
 		g := c.fg.new(groupMutex)
 		for _, f := range flags {
-			panicIfFlagAlreadyDefined(c, f)
-			c.fsl.set(f.name, f)
-			c.fss.set(f.short, f)
+			c.addFlag(f)
 			g.add(f)
-			f.cvalidators = append(f.cvalidators,
+			f.gvalidators = append(f.gvalidators,
 				func(f Flag) error {
 					g.met++
 					if g.met > 1 {
@@ -189,20 +336,13 @@ func WithMutualExclusiveFlags(flags ...*flag) commandOption {
 // This function takes a variable number of flags as its arguments.
 // The flags passed to this function will be added to the group,
 // and an error will not be thrown even if one of the flags is not set.
+// Values taken from environment variables and configuration files count as set.
 func WithAlwaysTogetherFlags(flags ...*flag) commandOption {
 	return func(c *command) {
 		g := c.fg.new(groupTogether)
 		for _, f := range flags {
-			panicIfFlagAlreadyDefined(c, f)
-			c.fsl.set(f.name, f)
-			c.fss.set(f.short, f)
+			c.addFlag(f)
 			g.add(f)
-			f.cvalidators = append(f.cvalidators,
-				func(_ Flag) error {
-					g.met++
-					return nil
-				},
-			)
 		}
 	}
 
@@ -241,27 +381,29 @@ func (c *command) Description() string {
 	return c.description
 }
 
+// Aliases method returns the alternative names of the command.
+func (c *command) Aliases() []string {
+	return c.aliases
+}
+
 // Deprecated method returns a boolean indicating whether the command is deprecated or not.
 func (c *command) Deprecated() bool {
 	return c.deprecated
 }
 
-// Subcommands method returns a slice of Command objects representing the subcommands of this command.
+// Hidden method returns a boolean indicating whether the command is hidden or not.
+func (c *command) Hidden() bool {
+	return c.hidden
+}
+
+// Subcommands method returns a slice of Command objects representing the visible subcommands of this command.
 func (c *command) Subcommands() []Command {
-	var subcommands []Command
-	for _, subcommand := range c.cs {
-		subcommands = append(subcommands, subcommand)
-	}
-	return subcommands
+	return view{command: c}.Subcommands()
 }
 
-// Flags method returns a slice of Flag objects representing the flags of this command.
+// Flags method returns a slice of Flag objects representing the visible flags of this command.
 func (c *command) Flags() []Flag {
-	var flags []Flag
-	for _, flag := range c.fsl {
-		flags = append(flags, flag)
-	}
-	return flags
+	return view{command: c}.Flags()
 }
 
 // Arguments method returns a slice of Argument objects representing the arguments of this command.
@@ -275,47 +417,306 @@ func (c *command) Arguments() []Argument {
 
 // Help method returns a string representing the help information for the command.
 func (c *command) Help() string {
-	var s strings.Builder
-	if err := helpTemplate.Execute(&s, c); err != nil {
-		panic(err)
-	}
-	return s.String()
+	return view{command: c}.Help()
+}
+
+// HelpAll method returns a string representing the help information for the command
+// including hidden flags and subcommands.
+func (c *command) HelpAll() string {
+	return view{command: c, all: true}.Help()
 }
 
 // The Run method is responsible for parsing the command line arguments and executing the command.
 func (c *command) Run() error {
-	p := newParser(os.Args[1:])
+	return c.RunArgs(os.Args[1:])
+}
+
+// RunArgs parses the given arguments instead of the command line arguments and executes the command.
+// The arguments must not include the program name.
+func (c *command) RunArgs(args []string) error {
+	return c.Execute(stdcontext.Background(), args)
+}
+
+// RunLine splits the given line into arguments the way a shell does and executes the command.
+// Single and double quotes and backslash escapes are supported, '#' is not treated as a comment.
+// The line must not include the program name.
+func (c *command) RunLine(line string) error {
+	args, err := splitWords(line, false)
+	if err != nil {
+		return err
+	}
+	return c.RunArgs(args)
+}
+
+// Execute parses the given arguments and executes the command.
+// The context is passed to the action of the executed command through its Context.
+// The arguments must not include the program name.
+func (c *command) Execute(ctx stdcontext.Context, args []string) error {
+	c.reset()
+
+	if c.responseFiles {
+		var err error
+		if args, err = expandResponseFiles(args); err != nil {
+			return err
+		}
+	}
+
+	p := newParser(args)
+	p.mode = c.mode
+	p.prefix = c.prefix
+	p.c = c
 
 	r, err := p.parse()
 	if err != nil {
 		return err
 	}
 
+	if c.mode == ParsePOSIX && len(r.beforeFlags) > 0 && len(r.flags) > 0 {
+		return fmt.Errorf(
+			"invalid syntax: met positional args %s before flags %v", r.beforeFlags, r.flags,
+		)
+	}
+
+	return p.c.run(ctx, r)
+}
+
+// resolve walks down the command tree using the leading arguments as subcommand names.
+// If prefix is true, a subcommand can be referred to by an unambiguous prefix of its name,
+// hidden subcommands can be referred to only by their full names.
+// A deprecated subcommand with a replacement is forwarded to the replacement, see enter.
+// It returns the deepest command met and the arguments which were not consumed.
+func (c *command) resolve(args []string, prefix bool) (*command, []string, error) {
 	currc := c
-	for len(r.beforeFlags) > 0 {
-		cname := r.beforeFlags[0]
+	for len(args) > 0 {
+		cname := args[0]
+
+		if _, ok := currc.cs.get(cname); !ok && prefix {
+			candidates := matchPrefix(currc.cs.visible(), cname)
+			if len(candidates) > 1 {
+				return nil, nil, fmt.Errorf(
+					"invalid command: ambiguous subcommand '%s' for command '%s', candidates are %s",
+					cname, currc.name, strings.Join(candidates, ", "),
+				)
+			}
+
+			if len(candidates) == 1 {
+				cname = candidates[0]
+			}
+		}
 
 		sc, ok := currc.cs.get(cname)
 		if !ok {
 			break
 		}
 
-		currc = sc
-		r.beforeFlags = r.beforeFlags[1:]
+		var err error
+		if currc, err = sc.enter(make(map[*command]bool)); err != nil {
+			return nil, nil, err
+		}
+		args = args[1:]
 	}
 
-	if len(r.beforeFlags) > 0 && len(r.flagSet) > 0 {
-		return fmt.Errorf(
-			"invalid syntax: met positional args %s before flags %v", r.beforeFlags, r.flagSet,
-		)
+	return currc, args, nil
+}
+
+// enter returns the command which is run when the command is met on the command line.
+// If the command is deprecated, it prints a warning and enters the replacement of the command if any,
+// so chains of replacements are followed. The visited commands are tracked to detect cycles.
+func (c *command) enter(visited map[*command]bool) (*command, error) {
+	if !c.deprecated {
+		return c, nil
+	}
+
+	if visited[c] {
+		return nil, fmt.Errorf("invalid command: replacements of deprecated command '%s' form a cycle", c.path())
+	}
+	visited[c] = true
+
+	if c.replacement == "" {
+		warnDeprecated("command", c.path(), "", c.message)
+		return c, nil
 	}
 
-	return currc.run(r)
+	rc := c.root()
+	for _, name := range strings.Fields(c.replacement) {
+		sc, ok := rc.cs.get(name)
+		if !ok {
+			return nil, fmt.Errorf(
+				"invalid command: replacement '%s' of deprecated command '%s' is not defined", c.replacement, c.path(),
+			)
+		}
+		rc = sc
+	}
+
+	warnDeprecated("command", c.path(), rc.path(), c.message)
+	return rc.enter(visited)
+}
+
+// path returns the names of the command and all its parents separated by spaces.
+func (c *command) path() string {
+	if c.parent == nil {
+		return c.name
+	}
+	return c.parent.path() + " " + c.name
+}
+
+// subpath returns the names of the command and all its parents except the root command.
+func (c *command) subpath() []string {
+	if c.parent == nil {
+		return nil
+	}
+	return append(c.parent.subpath(), c.name)
+}
+
+// root returns the root command of the command tree.
+func (c *command) root() *command {
+	if c.parent == nil {
+		return c
+	}
+	return c.parent.root()
+}
+
+// autoEnv returns the name of the environment variable derived for the flag of the command.
+// It returns an empty string if the root command has no environment prefix or the flag is short-only.
+func (c *command) autoEnv(f *flag) string {
+	prefix := c.root().envPrefix
+	if prefix == "" || f.noAutoEnv || f.name == "" {
+		return ""
+	}
+
+	parts := append([]string{prefix}, c.subpath()...)
+	parts = append(parts, f.name)
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, strings.Join(parts, "_"))
+}
+
+// envs returns the names of the environment variables bound to the flag of the command.
+func (c *command) envs(f *flag) []string {
+	if name := c.autoEnv(f); name != "" {
+		return append(append([]string{}, f.envs...), name)
+	}
+	return f.envs
+}
+
+// scope returns the flags accepted by the command by their long and short names:
+// the flags of the command and the persistent flags of its ancestors.
+// A flag of the command shadows a persistent flag of an ancestor with the same name.
+func (c *command) scope() (flagset, flagset) {
+	fsl, fss := make(flagset), make(flagset)
+
+	for curr := c; curr != nil; curr = curr.parent {
+		for name, f := range curr.fsl {
+			if curr == c || f.persistent {
+				fsl.set(name, f)
+			}
+		}
+
+		for name, f := range curr.fss {
+			if curr == c || f.persistent {
+				fss.set(name, f)
+			}
+		}
+	}
+
+	return fsl, fss
+}
+
+// scoped returns the flags accepted by the command: the flags of the command
+// and the persistent flags of its ancestors which are reachable by at least one name.
+func (c *command) scoped() []*flag {
+	fsl, fss := c.scope()
+
+	seen := make(map[*flag]bool)
+	for _, fs := range []flagset{fsl, fss} {
+		for _, f := range fs {
+			seen[f] = true
+		}
+	}
+
+	var flags []*flag
+	for curr := c; curr != nil; curr = curr.parent {
+		for _, f := range curr.fs {
+			if seen[f] {
+				flags = append(flags, f)
+				delete(seen, f)
+			}
+		}
+	}
+
+	return flags
+}
+
+// inherited returns the persistent flags of the ancestors of the command
+// which are not shadowed by the flags of the command.
+func (c *command) inherited() []*flag {
+	return c.scoped()[len(c.fs):]
+}
+
+// values returns the flags accepted by the command by their long names,
+// short-only flags are available by their short names.
+func (c *command) values() flagset {
+	fsl, fss := c.scope()
+	for name, f := range fss {
+		if f.name == "" {
+			fsl.set(name, f)
+		}
+	}
+	return fsl
+}
+
+// lookupFlag returns the flag accepted by the command with the given long or short name.
+func (c *command) lookupFlag(name string, isLong bool) (*flag, bool) {
+	fsl, fss := c.scope()
+	if isLong {
+		return fsl.get(name)
+	}
+	return fss.get(name)
+}
+
+// lookupNegation returns the negatable flag of the command which is negated by the given long name.
+// A long name always takes precedence over a negation.
+func (c *command) lookupNegation(name string) (*flag, bool) {
+	fsl, _ := c.scope()
+	if _, ok := fsl.get(name); ok || !strings.HasPrefix(name, negationPrefix) {
+		return nil, false
+	}
+
+	f, ok := fsl.get(strings.TrimPrefix(name, negationPrefix))
+	if !ok || !f.negatable {
+		return nil, false
+	}
+
+	return f, true
+}
+
+// longFlags returns the flags of the command by all their long names including negations.
+func (c *command) longFlags() flagset {
+	fsl, _ := c.scope()
+
+	fs := make(flagset, len(fsl))
+	for name, f := range fsl {
+		fs.set(name, f)
+		if f.negatable {
+			fs.set(negationPrefix+name, f)
+		}
+	}
+	return fs
 }
 
 func (c *command) init(r repository) error {
 	var argi int
 
+	for _, sentry := range r.ancestorFlags {
+		if err := sentry.c.bind(sentry.entry); err != nil {
+			return err
+		}
+	}
+
 	for _, arg := range r.beforeFlags {
 		if len(c.as) < argi+1 {
 			break
@@ -325,26 +726,10 @@ func (c *command) init(r repository) error {
 		argi++
 	}
 
-	var (
-		f  *flag
-		ok bool
-	)
-
-	for fname, fentry := range r.flagSet {
-		switch {
-		case fentry.isLong:
-			f, ok = c.fsl.get(fname)
-		case !fentry.isLong:
-			f, ok = c.fss.get(fname)
-		}
-
-		if !ok {
-			return fmt.Errorf(
-				"invalid flag: met unexpected flag '%s' for command '%s'", fname, c.name,
-			)
+	for _, fentry := range r.flags {
+		if err := c.bind(fentry); err != nil {
+			return err
 		}
-
-		f.value = fentry.value
 	}
 
 	for _, arg := range r.positionalArgs {
@@ -359,22 +744,111 @@ func (c *command) init(r repository) error {
 	return nil
 }
 
+// bind assigns the value of the flag met on the command line to the flag accepted by the command.
+// If the flag is deprecated, it prints a warning and forwards the value to the replacement of the flag if any.
+func (c *command) bind(fentry entry) error {
+	f, ok := c.lookupFlag(fentry.name, fentry.isLong)
+	if !ok {
+		return fmt.Errorf(
+			"invalid flag: met unexpected flag '%s' for command '%s'", fentry.name, c.name,
+		)
+	}
+
+	if err := f.set(fentry.value); err != nil {
+		return err
+	}
+
+	if !f.deprecated {
+		return nil
+	}
+
+	if f.replacement == "" {
+		warnDeprecated("flag", f.option(), "", f.message)
+		return nil
+	}
+
+	rf, ok := c.lookupFlag(f.replacement, true)
+	if !ok {
+		return fmt.Errorf(
+			"invalid flag: replacement '--%s' of deprecated flag '%s' is not defined for command '%s'",
+			f.replacement, f.option(), c.name,
+		)
+	}
+
+	if rf.ttype != f.ttype {
+		return fmt.Errorf(
+			"invalid flag: replacement '%s' of type %s of deprecated flag '%s' of type %s for command '%s'",
+			rf.option(), rf.ttype, f.option(), f.ttype, c.name,
+		)
+	}
+
+	warnDeprecated("flag", f.option(), rf.option(), f.message)
+	return rf.set(fentry.value)
+}
+
 func (c *command) validate() error {
-	for _, f := range c.fsl {
-		if err := f.validate(); err != nil {
+	var (
+		content map[string]any
+		path    string
+	)
+
+	if cfg := c.root().config; cfg != nil {
+		var err error
+		if content, path, err = cfg.load(); err != nil {
 			return err
 		}
 	}
 
-	for _, g := range c.fg {
-		if g.ttype == groupTogether && 0 < g.met && g.met < len(g.flags) {
-			var flags []string
+	scoped := make(map[*flag]bool)
+	for _, f := range c.scoped() {
+		scoped[f] = true
+	}
+
+	for curr := c; curr != nil; curr = curr.parent {
+		section := curr.section(content)
+		for _, f := range curr.fs {
+			if !scoped[f] && f.source != SourceCommandLine {
+				continue
+			}
+
+			if err := curr.fill(f, section, path); err != nil {
+				return err
+			}
+
+			if err := f.validate(); err != nil {
+				return err
+			}
+
+			if f.required && f.value == nil {
+				return fmt.Errorf(
+					"invalid flags: missing required flag '%s' for command '%s'", f.option(), c.path(),
+				)
+			}
+		}
+	}
+
+	for curr := c; curr != nil; curr = curr.parent {
+		for _, g := range curr.fg {
+			if g.ttype != groupTogether {
+				continue
+			}
+
+			var met int
 			for _, f := range g.flags {
-				flags = append(flags, f.name)
+				if f.isSet() {
+					met++
+				}
+			}
+
+			if 0 < met && met < len(g.flags) {
+				var flags []string
+				for _, f := range g.flags {
+					flags = append(flags, f.key())
+				}
+				return fmt.Errorf(
+					"invalid flags: you have to pass flags %s together", flags,
+				)
 			}
-			return fmt.Errorf(
-				"invalid flags: you have to pass flags %s together", flags,
-			)
 		}
 	}
 
@@ -387,7 +861,31 @@ func (c *command) validate() error {
 	return nil
 }
 
-func (c *command) run(r repository) error {
+// fill sets the value of the flag which is not passed on the command line
+// from the environment variables or, if they are not set, from the section of the configuration file.
+func (c *command) fill(f *flag, section map[string]any, path string) error {
+	if f.source == SourceCommandLine {
+		return nil
+	}
+
+	for _, name := range c.envs(f) {
+		if v := os.Getenv(name); v != "" {
+			return f.fill(v, SourceEnv, name)
+		}
+	}
+
+	if v, ok := section[f.name]; ok && v != nil {
+		v, err := configValue(v, f.repeatable)
+		if err != nil {
+			return fmt.Errorf("invalid flag: flag '%s' from config file '%s': %w", f.name, path, err)
+		}
+		return f.fill(v, SourceConfig, path)
+	}
+
+	return nil
+}
+
+func (c *command) run(ctx stdcontext.Context, r repository) error {
 	if err := c.init(r); err != nil {
 		return err
 	}
@@ -404,30 +902,99 @@ func (c *command) run(r repository) error {
 		return nil
 	}
 
-	return c.action(&context{fs: c.fsl, as: c.as})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.action(&context{Context: ctx, fs: c.values(), as: c.as, trailing: r.trailingArgs})
+}
+
+// reset clears the values of flags and arguments of the command and its subcommands
+// which were set by the previous run.
+func (c *command) reset() {
+	for _, f := range c.fs {
+		f.value = nil
+		f.source, f.detail = SourceNone, ""
+	}
+
+	for _, g := range c.fg {
+		g.met = 0
+	}
+
+	for _, arg := range c.as {
+		arg.value = nil
+	}
+
+	for _, sc := range c.cs.commands() {
+		sc.reset()
+	}
 }
 
+// help prints the help message of the command if the help flag is passed
+// for the command or for any of its ancestors, e.g. 'app --help deploy'.
+// The '--help-all' flag prints the help message including hidden flags and subcommands.
 func (c *command) help() bool {
-	if v, _ := c.fsl.get("help"); v.value != nil {
-		fmt.Print(c.Help())
-		return true
+	for curr := c; curr != nil; curr = curr.parent {
+		if v, _ := curr.fsl.get("help-all"); v.value != nil {
+			fmt.Print(c.HelpAll())
+			return true
+		}
+	}
+
+	for curr := c; curr != nil; curr = curr.parent {
+		if v, _ := curr.fsl.get("help"); v.value != nil {
+			fmt.Print(c.Help())
+			return true
+		}
 	}
+
 	return false
 }
 
+// addFlag registers the flag for the command under all its long and short names.
+func (c *command) addFlag(f *flag) {
+	panicIfFlagAlreadyDefined(c, f)
+
+	c.fs = append(c.fs, f)
+	for _, name := range f.longNames() {
+		c.fsl.set(name, f)
+	}
+	for _, short := range f.shortNames() {
+		c.fss.set(short, f)
+	}
+}
+
 func panicIfFlagAlreadyDefined(c *command, f *flag) {
-	if _, ok := c.fsl.get(f.name); ok {
-		panic(fmt.Errorf(
-			"invalid command: long flag '%s' is alredy defined for command '%s'",
-			f.name, c.name,
-		))
+	for _, name := range f.longNames() {
+		if _, ok := c.fsl.get(name); ok {
+			panic(fmt.Errorf(
+				"invalid command: long flag '%s' is alredy defined for command '%s'",
+				name, c.name,
+			))
+		}
+
+		if _, ok := c.lookupNegation(name); ok {
+			panic(fmt.Errorf(
+				"invalid command: long flag '%s' is alredy defined as negation for command '%s'",
+				name, c.name,
+			))
+		}
+
+		if _, ok := c.fsl.get(negationPrefix + name); ok && f.negatable {
+			panic(fmt.Errorf(
+				"invalid command: long flag '%s' is alredy defined for command '%s'",
+				negationPrefix+name, c.name,
+			))
+		}
 	}
 
-	if _, ok := c.fss.get(f.short); ok {
-		panic(fmt.Errorf(
-			"invalid command: short flag '%s' is alredy defined for command '%s'",
-			f.short, c.name,
-		))
+	for _, short := range f.shortNames() {
+		if _, ok := c.fss.get(short); ok {
+			panic(fmt.Errorf(
+				"invalid command: short flag '%s' is alredy defined for command '%s'",
+				short, c.name,
+			))
+		}
 	}
 }
 
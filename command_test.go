@@ -1,13 +1,17 @@
 package yacli
 
 import (
+	"bytes"
+	stdcontext "context"
 	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
-func newCommand() *command {
-	return NewRootCommand(
+func newCommand(opts ...commandOption) *command {
+	return NewRootCommand(append([]commandOption{
 		WithFlags(
 			NewFlag("amount", "n", "…", Integer),
 		),
@@ -19,7 +23,7 @@ func newCommand() *command {
 			NewFlag("separator", "s", "…", String),
 			NewFlag("separator-amount", "a", "…", Integer),
 		),
-	)
+	}, opts...)...)
 }
 
 func TestCommand_run(t *testing.T) {
@@ -104,6 +108,283 @@ func TestCommand_run(t *testing.T) {
 			c:    newCommand(),
 			f:    []func(Context) error{nil},
 		},
+		{
+			name: "echo --amount 10 -- -rf --weird-name",
+			args: []string{"echo", "--amount", "10", "--", "-rf", "--weird-name"},
+			c:    newCommand(),
+			f: []func(Context) error{
+				func(ctx Context) error {
+					if n, isSet := ctx.Flags().Integer("amount"); !isSet || n != 10 {
+						return fmt.Errorf("amount expected 10, got %d", n)
+					}
+					if trailing := ctx.Trailing(); len(trailing) != 2 || trailing[0] != "-rf" || trailing[1] != "--weird-name" {
+						return fmt.Errorf("trailing expected [-rf --weird-name], got %v", trailing)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name: "echo smth --amount 10",
+			args: []string{"echo", "smth", "--amount", "10"},
+			c:    newCommand(),
+			f:    []func(Context) error{nil},
+		},
+		{
+			name:   "echo smth --amount 10 in posix mode",
+			args:   []string{"echo", "smth", "--amount", "10"},
+			c:      newCommand(WithParseMode(ParsePOSIX)),
+			hasErr: true,
+			f:      []func(Context) error{nil},
+		},
+		{
+			name: "echo --amount -10 -5",
+			args: []string{"echo", "--amount", "-10", "-5"},
+			c:    newCommand(WithArguments(NewArgument("x", "…", Integer))),
+			f: []func(Context) error{
+				func(ctx Context) error {
+					if n, isSet := ctx.Flags().Integer("amount"); !isSet || n != -10 {
+						return fmt.Errorf("amount expected -10, got %d", n)
+					}
+					if x := ctx.Arguments().Integer("x"); x != -5 {
+						return fmt.Errorf("x expected -5, got %d", x)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name: "echo --tag a -t b --tag=c",
+			args: []string{"echo", "--tag", "a", "-t", "b", "--tag=c"},
+			c:    newCommand(WithFlags(NewFlag("tag", "t", "…", String, WithFlagRepeatable(true)))),
+			f: []func(Context) error{
+				func(ctx Context) error {
+					tags, isSet := ctx.Flags().Strings("tag")
+					if !isSet || !reflect.DeepEqual(tags, []string{"a", "b", "c"}) {
+						return fmt.Errorf("tag expected [a b c], got %v", tags)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name: "echo --port 1 --port 2",
+			args: []string{"echo", "--port", "1", "--port", "2"},
+			c:    newCommand(WithFlags(NewFlag("port", "p", "…", Integer, WithFlagRepeatable(true)))),
+			f: []func(Context) error{
+				func(ctx Context) error {
+					ports, isSet := ctx.Flags().Integers("port")
+					if !isSet || !reflect.DeepEqual(ports, []int{1, 2}) {
+						return fmt.Errorf("port expected [1 2], got %v", ports)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name: "echo --amount 1 --amount 2",
+			args: []string{"echo", "--amount", "1", "--amount", "2"},
+			c:    newCommand(),
+			f: []func(Context) error{
+				func(ctx Context) error {
+					if n, isSet := ctx.Flags().Integer("amount"); !isSet || n != 2 {
+						return fmt.Errorf("amount expected 2, got %d", n)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name: "echo --limit 1 --limit 2 with first wins policy",
+			args: []string{"echo", "--limit", "1", "--limit", "2"},
+			c:    newCommand(WithFlags(NewFlag("limit", "m", "…", Integer, WithFlagDuplicates(DuplicateFirstWins)))),
+			f: []func(Context) error{
+				func(ctx Context) error {
+					if n, isSet := ctx.Flags().Integer("limit"); !isSet || n != 1 {
+						return fmt.Errorf("limit expected 1, got %d", n)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name:   "echo --limit 1 -m 2 with error policy",
+			args:   []string{"echo", "--limit", "1", "-m", "2"},
+			c:      newCommand(WithFlags(NewFlag("limit", "m", "…", Integer, WithFlagDuplicates(DuplicateError)))),
+			hasErr: true,
+			f:      []func(Context) error{nil},
+		},
+		{
+			name: "echo -vvv",
+			args: []string{"echo", "-vvv"},
+			c:    newCommand(WithFlags(NewFlag("verbose", "v", "…", Count))),
+			f: []func(Context) error{
+				func(ctx Context) error {
+					if n, isSet := ctx.Flags().Count("verbose"); !isSet || n != 3 {
+						return fmt.Errorf("verbose expected 3, got %d", n)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name: "echo -v -v -v",
+			args: []string{"echo", "-v", "-v", "-v"},
+			c:    newCommand(WithFlags(NewFlag("verbose", "v", "…", Count))),
+			f: []func(Context) error{
+				func(ctx Context) error {
+					if n, isSet := ctx.Flags().Count("verbose"); !isSet || n != 3 {
+						return fmt.Errorf("verbose expected 3, got %d", n)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name: "echo --verbose --verbose -vn 10",
+			args: []string{"echo", "--verbose", "--verbose", "-vn", "10"},
+			c:    newCommand(WithFlags(NewFlag("verbose", "v", "…", Count))),
+			f: []func(Context) error{
+				func(ctx Context) error {
+					if n, isSet := ctx.Flags().Count("verbose"); !isSet || n != 3 {
+						return fmt.Errorf("verbose expected 3, got %d", n)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name:   "echo -\\xff",
+			args:   []string{"echo", "-\xff"},
+			c:      newCommand(WithFlags(NewFlag("verbose", "v", "…", Bool))),
+			hasErr: true,
+			f:      []func(Context) error{nil},
+		},
+		{
+			name:   "echo -v\\xff",
+			args:   []string{"echo", "-v\xff"},
+			c:      newCommand(WithFlags(NewFlag("verbose", "v", "…", Bool))),
+			hasErr: true,
+			f:      []func(Context) error{nil},
+		},
+		{
+			name: "echo --verbose=3",
+			args: []string{"echo", "--verbose=3"},
+			c:    newCommand(WithFlags(NewFlag("verbose", "v", "…", Count))),
+			f: []func(Context) error{
+				func(ctx Context) error {
+					if n, isSet := ctx.Flags().Count("verbose"); !isSet || n != 3 {
+						return fmt.Errorf("verbose expected 3, got %d", n)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name:   "echo --verbose=many",
+			args:   []string{"echo", "--verbose=many"},
+			c:      newCommand(WithFlags(NewFlag("verbose", "v", "…", Count))),
+			hasErr: true,
+			f:      []func(Context) error{nil},
+		},
+		{
+			name: "echo --verbose --no-verbose",
+			args: []string{"echo", "--verbose", "--no-verbose"},
+			c:    newCommand(WithFlags(NewFlag("verbose", "v", "…", Bool, WithFlagNegatable(true)))),
+			f: []func(Context) error{
+				func(ctx Context) error {
+					if v, isSet := ctx.Flags().Bool("verbose"); !isSet || v {
+						return fmt.Errorf("verbose expected false, got %v (isSet=%v)", v, isSet)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name:   "echo --no-verbose=true",
+			args:   []string{"echo", "--no-verbose=true"},
+			c:      newCommand(WithFlags(NewFlag("verbose", "v", "…", Bool, WithFlagNegatable(true)))),
+			hasErr: true,
+			f:      []func(Context) error{nil},
+		},
+		{
+			name:   "echo --no-cache without negation",
+			args:   []string{"echo", "--no-cache"},
+			c:      newCommand(WithFlags(NewFlag("cache", "c", "…", Bool))),
+			hasErr: true,
+			f:      []func(Context) error{nil},
+		},
+		{
+			name: "echo --color --no-quiet",
+			args: []string{"echo", "--color", "--no-quiet"},
+			c: newCommand(WithMutualExclusiveFlags(
+				NewFlag("color", "c", "…", Bool, WithFlagNegatable(true)),
+				NewFlag("quiet", "q", "…", Bool, WithFlagNegatable(true)),
+			)),
+			hasErr: true,
+			f:      []func(Context) error{nil},
+		},
+		{
+			name: "echo with defaults",
+			args: []string{"echo"},
+			c: newCommand(
+				WithFlags(
+					NewFlag("limit", "m", "…", Integer, WithFlagDefault(10), WithFlagValidator(func(f Flag) error {
+						if f.Value() != 10 {
+							return fmt.Errorf("limit expected 10 in validator, got %v", f.Value())
+						}
+						return nil
+					})),
+					NewFlag("tag", "t", "…", String, WithFlagRepeatable(true), WithFlagDefault([]string{"a", "b"})),
+				),
+				WithArguments(
+					NewArgument("message", "…", String, WithArgumentDefault("hello")),
+					NewArgument("suffix", "…", String, WithArgumentOptional(true)),
+				),
+			),
+			f: []func(Context) error{
+				func(ctx Context) error {
+					if n, isSet := ctx.Flags().Integer("limit"); !isSet || n != 10 {
+						return fmt.Errorf("limit expected 10, got %d", n)
+					}
+					if tags, isSet := ctx.Flags().Strings("tag"); !isSet || !reflect.DeepEqual(tags, []string{"a", "b"}) {
+						return fmt.Errorf("tag expected [a b], got %v", tags)
+					}
+					if message := ctx.Arguments().String("message"); message != "hello" {
+						return fmt.Errorf("message expected 'hello', got '%s'", message)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name: "echo --limit 5 world",
+			args: []string{"echo", "--limit", "5", "world"},
+			c: newCommand(
+				WithFlags(NewFlag("limit", "m", "…", Integer, WithFlagDefault(10))),
+				WithArguments(NewArgument("message", "…", String, WithArgumentDefault("hello"))),
+			),
+			f: []func(Context) error{
+				func(ctx Context) error {
+					if n, isSet := ctx.Flags().Integer("limit"); !isSet || n != 5 {
+						return fmt.Errorf("limit expected 5, got %d", n)
+					}
+					if message := ctx.Arguments().String("message"); message != "world" {
+						return fmt.Errorf("message expected 'world', got '%s'", message)
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name: "echo --color with default in mutual exclusive group",
+			args: []string{"echo", "--color"},
+			c: newCommand(WithMutualExclusiveFlags(
+				NewFlag("color", "c", "…", Bool),
+				NewFlag("plain", "p", "…", Bool, WithFlagDefault(false)),
+			)),
+			f: []func(Context) error{nil},
+		},
 		{
 			name:   "echo --separator smth",
 			args:   []string{"echo", "--separator", "smth"},
@@ -132,3 +413,726 @@ func TestCommand_run(t *testing.T) {
 		})
 	}
 }
+
+func TestCommand_RunArgs(t *testing.T) {
+	c := newCommand()
+
+	for _, args := range [][]string{{"--lowercase", "-n", "1"}, {"--uppercase"}} {
+		args := args
+		c.action = func(ctx Context) error {
+			if _, isSet := ctx.Flags().Integer("amount"); isSet && len(args) == 1 {
+				return fmt.Errorf("amount MUST NOT be set by the previous run")
+			}
+			return nil
+		}
+
+		if err := c.RunArgs(args); err != nil {
+			t.Errorf("expected no error for %v, got %v", args, err)
+		}
+	}
+}
+
+func TestCommand_Execute(t *testing.T) {
+	type key struct{}
+
+	c := newCommand(WithAction(func(ctx Context) error {
+		if v, _ := ctx.Value(key{}).(string); v != "value" {
+			return fmt.Errorf("context value expected 'value', got '%s'", v)
+		}
+		return nil
+	}))
+
+	ctx := stdcontext.WithValue(stdcontext.Background(), key{}, "value")
+	if err := c.Execute(ctx, []string{"-n", "1"}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	ctx, cancel := stdcontext.WithCancel(ctx)
+	cancel()
+	if err := c.Execute(ctx, []string{"-n", "1"}); err != stdcontext.Canceled {
+		t.Errorf("expected %v, got %v", stdcontext.Canceled, err)
+	}
+}
+
+func TestCommand_RunLine(t *testing.T) {
+	testCases := []struct {
+		name   string
+		line   string
+		want   string
+		hasErr bool
+	}{
+		{name: "unquoted", line: "sum 1 2", want: "3"},
+		{name: "quoted", line: `sum 1 "2"`, want: "3"},
+		{name: "quoted flag value", line: `sum --label 'x + y' 1 2`, want: "x + y = 3"},
+		{name: "escaped flag value", line: `sum --label x\ +\ y 1 2`, want: "x + y = 3"},
+		{name: "unterminated quote", line: `sum --label "x + y 1 2`, hasErr: true},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+
+			c := NewCommand("calc", WithSubcommand(NewCommand(
+				"sum",
+				WithFlags(NewFlag("label", "l", "…", String)),
+				WithArguments(
+					NewArgument("x", "…", Integer),
+					NewArgument("y", "…", Integer),
+				),
+				WithAction(func(ctx Context) error {
+					got = fmt.Sprint(ctx.Arguments().Integer("x") + ctx.Arguments().Integer("y"))
+					if label, isSet := ctx.Flags().String("label"); isSet {
+						got = fmt.Sprintf("%s = %s", label, got)
+					}
+					return nil
+				}),
+			)))
+
+			err := c.RunLine(tt.line)
+			if (err != nil) != tt.hasErr {
+				t.Fatalf("expected error %v, got %v", tt.hasErr, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("expected '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCommand_Help(t *testing.T) {
+	c := newCommand(WithFlags(
+		NewFlag("verbose", "v", "…", Bool, WithFlagNegatable(true)),
+		NewFlag("color", "c", "…", String, WithFlagOptionalValue("auto"), WithFlagPlaceholder("WHEN")),
+		NewFlag("limit", "m", "…", Integer, WithFlagDefault(10)),
+	))
+
+	for _, want := range []string{"--[no-]verbose", "--amount", "--color[=WHEN]", "(default: 10)"} {
+		if !strings.Contains(c.Help(), want) {
+			t.Errorf("expected help to contain '%s', got:\n%s", want, c.Help())
+		}
+	}
+}
+
+func TestCommand_defaultType(t *testing.T) {
+	testCases := []struct {
+		name string
+		f    func()
+	}{
+		{"flag", func() { NewFlag("limit", "m", "…", Integer, WithFlagDefault("10")) }},
+		{"repeatable flag", func() { NewFlag("tag", "t", "…", String, WithFlagRepeatable(true), WithFlagDefault("a")) }},
+		{"argument", func() { NewArgument("x", "…", Float64, WithArgumentDefault(float32(1))) }},
+		{"optional value", func() { NewFlag("n", "", "…", Integer, WithFlagOptionalValue("abc")) }},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic on default value of incorrect type")
+				}
+			}()
+			tt.f()
+		})
+	}
+}
+
+func TestCommand_countType(t *testing.T) {
+	testCases := []struct {
+		name string
+		f    func()
+	}{
+		{"repeatable", func() { NewFlag("verbose", "v", "…", Count, WithFlagRepeatable(true)) }},
+		{"optional value", func() { NewFlag("verbose", "v", "…", Count, WithFlagOptionalValue("2")) }},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic on %s counter flag", tt.name)
+				}
+			}()
+			tt.f()
+		})
+	}
+}
+
+func TestCommand_required(t *testing.T) {
+	c := NewCommand("app", WithSubcommand(NewCommand(
+		"deploy",
+		WithFlags(
+			NewFlag("token", "t", "…", String, WithFlagRequired()),
+			NewFlag("region", "r", "…", String),
+		),
+	)))
+
+	err := c.RunArgs([]string{"deploy", "-r", "eu"})
+	if err == nil || !strings.Contains(err.Error(), "--token") || !strings.Contains(err.Error(), "app deploy") {
+		t.Errorf("expected error naming '--token' and 'app deploy', got %v", err)
+	}
+
+	if err := c.RunArgs([]string{"deploy", "--token", "secret"}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	deploy, _ := c.cs.get("deploy")
+	if usage := deploy.Usage(); !strings.Contains(usage, " -t") || strings.Contains(usage, "[ -t ]") {
+		t.Errorf("expected usage to contain required ' -t', got '%s'", usage)
+	}
+}
+
+func TestCommand_env(t *testing.T) {
+	t.Setenv("APP_TOKEN", "")
+	t.Setenv("TOKEN", "secret")
+	t.Setenv("APP_LIMIT", "5")
+
+	var (
+		token string
+		limit int
+	)
+
+	c := NewCommand("app",
+		WithFlags(
+			NewFlag("token", "t", "…", String, WithFlagEnv("APP_TOKEN", "TOKEN"), WithFlagRequired()),
+			NewFlag("limit", "l", "…", Integer, WithFlagEnv("APP_LIMIT"), WithFlagDefault(10)),
+		),
+		WithAction(func(ctx Context) error {
+			token, _ = ctx.Flags().String("token")
+			limit, _ = ctx.Flags().Integer("limit")
+			return nil
+		}),
+	)
+
+	if err := c.RunArgs(nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if token != "secret" || limit != 5 {
+		t.Errorf("expected token 'secret' and limit 5 from environment, got '%s' and %d", token, limit)
+	}
+
+	if err := c.RunArgs([]string{"--token", "flag", "-l", "7"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if token != "flag" || limit != 7 {
+		t.Errorf("expected token 'flag' and limit 7 from command line, got '%s' and %d", token, limit)
+	}
+
+	t.Setenv("APP_LIMIT", "five")
+	if err := c.RunArgs(nil); err == nil {
+		t.Errorf("expected error for invalid environment value")
+	}
+
+	if help := c.Help(); !strings.Contains(help, "[env: APP_TOKEN, TOKEN]") {
+		t.Errorf("expected help to contain bound environment variables, got:\n%s", help)
+	}
+}
+
+func TestCommand_envGroups(t *testing.T) {
+	t.Setenv("SEP", ",")
+	t.Setenv("JSON", "true")
+
+	c := NewCommand("app",
+		WithAlwaysTogetherFlags(
+			NewFlag("sep", "s", "…", String, WithFlagEnv("SEP")),
+			NewFlag("count", "n", "…", Integer),
+		),
+		WithMutualExclusiveFlags(
+			NewFlag("json", "j", "…", Bool, WithFlagEnv("JSON")),
+			NewFlag("yaml", "y", "…", Bool),
+		),
+	)
+
+	if err := c.RunArgs([]string{"-n", "2"}); err != nil {
+		t.Errorf("expected environment value to count for flags passed together, got %v", err)
+	}
+
+	if err := c.RunArgs([]string{"-n", "2", "--yaml"}); err != nil {
+		t.Errorf("expected environment value to be ignored for mutually exclusive flags, got %v", err)
+	}
+
+	t.Setenv("SEP", "")
+	if err := c.RunArgs([]string{"-n", "2"}); err == nil {
+		t.Errorf("expected error for flags not passed together")
+	}
+}
+
+func TestCommand_envPrefix(t *testing.T) {
+	t.Setenv("MYAPP_DEPLOY_DRY_RUN", "true")
+	t.Setenv("MYAPP_DEPLOY_REGION", "eu")
+	t.Setenv("MYAPP_VERBOSE", "2")
+
+	var (
+		dryRun, isRegionSet bool
+		verbose             int
+	)
+
+	deploy := NewCommand("deploy",
+		WithFlags(
+			NewFlag("dry-run", "d", "…", Bool),
+			NewFlag("region", "r", "…", String, WithFlagNoAutoEnv()),
+		),
+		WithAction(func(ctx Context) error {
+			dryRun, _ = ctx.Flags().Bool("dry-run")
+			_, isRegionSet = ctx.Flags().String("region")
+			return nil
+		}),
+	)
+
+	c := NewCommand("myapp",
+		WithEnvPrefix("MYAPP"),
+		WithFlags(NewFlag("verbose", "v", "…", Count)),
+		WithSubcommand(deploy),
+		WithAction(func(ctx Context) error {
+			verbose, _ = ctx.Flags().Count("verbose")
+			return nil
+		}),
+	)
+
+	if err := c.RunArgs([]string{"deploy"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !dryRun || isRegionSet {
+		t.Errorf("expected dry-run from environment and region unset, got %v and %v", dryRun, isRegionSet)
+	}
+
+	if err := c.RunArgs(nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if verbose != 2 {
+		t.Errorf("expected verbose 2 from environment, got %d", verbose)
+	}
+
+	help := deploy.Help()
+	if !strings.Contains(help, "Environment:") || !strings.Contains(help, "MYAPP_DEPLOY_DRY_RUN") {
+		t.Errorf("expected help to list derived environment variables, got:\n%s", help)
+	}
+	if strings.Contains(help, "MYAPP_VERBOSE") {
+		t.Errorf("expected help not to list variables of flags of the parent, got:\n%s", help)
+	}
+	if strings.Contains(help, "MYAPP_DEPLOY_REGION") || strings.Contains(help, "MYAPP_DEPLOY_HELP") {
+		t.Errorf("expected help not to list opted out flags, got:\n%s", help)
+	}
+
+	zone := NewFlag("zone", "z", "…", String)
+	sub := NewCommand("deploy", WithFlags(zone))
+	NewCommand("/opt/My Tools/myapp", WithEnvPrefix("MYAPP"), WithSubcommand(sub))
+	if name := sub.autoEnv(zone); name != "MYAPP_DEPLOY_ZONE" {
+		t.Errorf("expected 'MYAPP_DEPLOY_ZONE' for root command name with spaces, got '%s'", name)
+	}
+}
+
+func TestCommand_persistent(t *testing.T) {
+	t.Setenv("APP_LEVEL", "3")
+
+	var (
+		verbose bool
+		level   int
+		name    string
+	)
+
+	deploy := NewCommand("deploy",
+		WithFlags(NewFlag("name", "n", "…", String)),
+		WithAction(func(ctx Context) error {
+			verbose, _ = ctx.Flags().Bool("verbose")
+			level, _ = ctx.Flags().Integer("level")
+			name, _ = ctx.Flags().String("name")
+			return nil
+		}),
+	)
+
+	c := NewCommand("app",
+		WithEnvPrefix("APP"),
+		WithPersistentFlags(
+			NewFlag("verbose", "v", "…", Bool),
+			NewFlag("level", "l", "…", Integer),
+			NewFlag("name", "n", "…", String, WithFlagRequired()),
+		),
+		WithSubcommand(NewCommand("group", WithSubcommand(deploy))),
+	)
+
+	if err := c.RunArgs([]string{"group", "deploy", "--verbose", "-n", "x"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !verbose || level != 3 || name != "x" {
+		t.Errorf("expected verbose, level 3 and name 'x', got %v, %d and '%s'", verbose, level, name)
+	}
+
+	if err := c.RunArgs([]string{"group", "--verbose"}); err == nil {
+		t.Errorf("expected error for missing required persistent flag")
+	}
+
+	name = ""
+	if err := c.RunArgs([]string{"deploy", "--verbose", "-n", "x"}); err != nil || name != "" {
+		t.Errorf("expected deploy not to be reachable from the root command, got name '%s' and %v", name, err)
+	}
+
+	help := deploy.Help()
+	if !strings.Contains(help, "Global Flags:") || !strings.Contains(help, "--verbose") {
+		t.Errorf("expected help to list global flags, got:\n%s", help)
+	}
+	if !strings.Contains(help, "APP_LEVEL") || strings.Contains(help, "APP_GROUP_DEPLOY_LEVEL") {
+		t.Errorf("expected help to list variables of global flags, got:\n%s", help)
+	}
+}
+
+func TestCommand_ancestorFlags(t *testing.T) {
+	var (
+		verbose bool
+		env     string
+	)
+
+	deploy := NewCommand("deploy",
+		WithArguments(NewArgument("env", "…", String)),
+		WithAction(func(ctx Context) error {
+			verbose, _ = ctx.Flags().Bool("verbose")
+			env = ctx.Arguments().String("env")
+			return nil
+		}),
+	)
+
+	dry := NewFlag("dry", "d", "…", Bool)
+	c := NewCommand("app",
+		WithFlags(dry),
+		WithPersistentFlags(NewFlag("verbose", "v", "…", Bool)),
+		WithSubcommand(NewCommand("cloud", WithSubcommand(deploy))),
+	)
+
+	testCases := []struct {
+		name   string
+		args   []string
+		dry    bool
+		hasErr bool
+	}{
+		{name: "flag after subcommand", args: []string{"cloud", "deploy", "-v", "prod"}},
+		{name: "flag before subcommand", args: []string{"-v", "cloud", "deploy", "prod"}},
+		{name: "flag between subcommands", args: []string{"cloud", "--verbose", "deploy", "prod"}},
+		{name: "parent flag before subcommand", args: []string{"-d", "-v", "cloud", "deploy", "prod"}, dry: true},
+		{name: "parent flag after subcommand", args: []string{"cloud", "deploy", "--dry", "-v", "prod"}, hasErr: true},
+		{name: "unknown flag before subcommand", args: []string{"--unknown", "cloud", "deploy", "prod"}, hasErr: true},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			verbose, env = false, ""
+
+			err := c.RunArgs(tt.args)
+			if (err != nil) != tt.hasErr {
+				t.Fatalf("expected error %v, got %v", tt.hasErr, err)
+			}
+
+			if tt.hasErr {
+				return
+			}
+
+			if !verbose || env != "prod" {
+				t.Errorf("expected verbose and env 'prod', got %v and '%s'", verbose, env)
+			}
+
+			if d, _ := dry.Value().(bool); d != tt.dry {
+				t.Errorf("expected dry %v, got %v", tt.dry, d)
+			}
+		})
+	}
+}
+
+func TestCommand_hidden(t *testing.T) {
+	var (
+		debug    bool
+		migrated bool
+	)
+
+	c := NewCommand("app",
+		WithPrefixMatching(true),
+		WithFlags(
+			NewFlag("debug", "d", "…", Bool, WithFlagHidden()),
+			NewFlag("debug-level", "l", "…", Integer),
+		),
+		WithSubcommand(NewCommand("migrate", WithCommandHidden(), WithAction(func(ctx Context) error {
+			migrated = true
+			return nil
+		}))),
+		WithSubcommand(NewCommand("deploy")),
+		WithAction(func(ctx Context) error {
+			debug, _ = ctx.Flags().Bool("debug")
+			return nil
+		}),
+	)
+
+	if err := c.RunArgs([]string{"--debug"}); err != nil || !debug {
+		t.Errorf("expected hidden flag to be parsed, got %v", err)
+	}
+
+	if err := c.RunArgs([]string{"migrate"}); err != nil || !migrated {
+		t.Errorf("expected hidden command to be run, got %v", err)
+	}
+
+	if err := c.RunArgs([]string{"mig"}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	for _, f := range c.Flags() {
+		if f.Hidden() {
+			t.Errorf("expected hidden flag '%s' to be filtered", f.Name())
+		}
+	}
+
+	if len(c.Subcommands()) != 1 {
+		t.Errorf("expected 1 visible subcommand, got %d", len(c.Subcommands()))
+	}
+
+	if usage := c.Usage(); strings.Contains(usage, "-d ") || strings.Contains(usage, "migrate") {
+		t.Errorf("expected hidden items to be filtered, got %s", usage)
+	}
+
+	if help := c.Help(); strings.Contains(help, formatBold("--debug")) || strings.Contains(help, "migrate") {
+		t.Errorf("expected hidden items to be filtered, got:\n%s", help)
+	}
+
+	help := c.HelpAll()
+	for _, want := range []string{formatBold("--debug"), "migrate", "--help-all"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("expected help to contain '%s', got:\n%s", want, help)
+		}
+	}
+}
+
+func TestCommand_flagNames(t *testing.T) {
+	var (
+		dryRun  bool
+		exclude bool
+		output  string
+	)
+
+	c := NewCommand("app",
+		WithFlags(
+			NewFlag("dry-run", "n", "…", Bool, WithFlagAliases("dryrun"), WithFlagShortAliases("N")),
+			NewFlag("", "x", "…", Bool),
+			NewFlag("output", "", "…", String),
+			NewFlag("format", "", "…", String),
+		),
+		WithAction(func(ctx Context) error {
+			dryRun, _ = ctx.Flags().Bool("dry-run")
+			exclude, _ = ctx.Flags().Bool("x")
+			output, _ = ctx.Flags().String("output")
+			return nil
+		}),
+	)
+
+	for _, args := range [][]string{{"--dry-run"}, {"--dryrun"}, {"-n"}, {"-N"}} {
+		dryRun = false
+		if err := c.RunArgs(args); err != nil || !dryRun {
+			t.Errorf("expected %v to set the flag, got %v", args, err)
+		}
+	}
+
+	if err := c.RunArgs([]string{"-x", "--output", "out.txt"}); err != nil || !exclude || output != "out.txt" {
+		t.Errorf("expected short-only and long-only flags to be set, got %v", err)
+	}
+
+	if n := len(c.Flags()); n != 5 {
+		t.Errorf("expected 5 flags, got %d", n)
+	}
+
+	help := c.Help()
+	for _, want := range []string{formatBold("--dryrun"), formatBold("-N"), formatBold("-x"), formatBold("--output")} {
+		if !strings.Contains(help, want) {
+			t.Errorf("expected help to contain '%s', got:\n%s", want, help)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for duplicate alias")
+		}
+	}()
+	NewCommand("app", WithFlags(
+		NewFlag("dry-run", "n", "…", Bool),
+		NewFlag("simulate", "s", "…", Bool, WithFlagAliases("dry-run")),
+	))
+}
+
+func TestCommand_deprecation(t *testing.T) {
+	var buf bytes.Buffer
+	warnings = &buf
+	defer func() { warnings = os.Stderr }()
+
+	var (
+		region string
+		ran    string
+	)
+
+	deploy := NewCommand("deploy",
+		WithFlags(
+			NewFlag("region", "r", "…", String),
+			NewFlag("zone", "z", "…", String, WithFlagReplacement("region")),
+			NewFlag("fast", "f", "…", Bool, WithFlagDeprecationMessage("it is always fast now")),
+		),
+		WithAction(func(ctx Context) error {
+			ran = "deploy"
+			region, _ = ctx.Flags().String("region")
+			return nil
+		}),
+	)
+
+	c := NewCommand("app",
+		WithSubcommand(NewCommand("cluster", WithSubcommand(deploy))),
+		WithSubcommand(NewCommand("ship", WithCommandReplacement("cluster deploy"))),
+		WithSubcommand(NewCommand("push", WithCommandReplacement("ship"))),
+		WithSubcommand(NewCommand("legacy",
+			WithCommandDeprecationMessage("it will be removed"),
+			WithAction(func(ctx Context) error {
+				ran = "legacy"
+				return nil
+			}),
+		)),
+	)
+
+	testCases := []struct {
+		name    string
+		args    []string
+		ran     string
+		region  string
+		warning string
+	}{
+		{
+			name:    "replaced flag",
+			args:    []string{"cluster", "deploy", "--zone", "eu"},
+			ran:     "deploy",
+			region:  "eu",
+			warning: "warning: flag '--zone' is deprecated, use '--region' instead\n",
+		},
+		{
+			name:    "deprecated flag",
+			args:    []string{"cluster", "deploy", "-f", "-r", "us"},
+			ran:     "deploy",
+			region:  "us",
+			warning: "warning: flag '--fast' is deprecated: it is always fast now\n",
+		},
+		{
+			name:    "replaced command",
+			args:    []string{"ship", "-r", "eu"},
+			ran:     "deploy",
+			region:  "eu",
+			warning: "warning: command 'app ship' is deprecated, use 'app cluster deploy' instead\n",
+		},
+		{
+			name:   "replaced command chain",
+			args:   []string{"push", "-r", "eu"},
+			ran:    "deploy",
+			region: "eu",
+			warning: "warning: command 'app push' is deprecated, use 'app ship' instead\n" +
+				"warning: command 'app ship' is deprecated, use 'app cluster deploy' instead\n",
+		},
+		{
+			name:    "deprecated command",
+			args:    []string{"legacy"},
+			ran:     "legacy",
+			warning: "warning: command 'app legacy' is deprecated: it will be removed\n",
+		},
+		{
+			name:   "not deprecated",
+			args:   []string{"cluster", "deploy", "-r", "eu"},
+			ran:    "deploy",
+			region: "eu",
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			ran, region = "", ""
+
+			if err := c.RunArgs(tt.args); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if ran != tt.ran || region != tt.region {
+				t.Errorf("expected command '%s' with region '%s', got '%s' with '%s'", tt.ran, tt.region, ran, region)
+			}
+
+			if buf.String() != tt.warning {
+				t.Errorf("expected warning %q, got %q", tt.warning, buf.String())
+			}
+		})
+	}
+
+	cycle := NewCommand("app", WithSubcommand(NewCommand("a", WithCommandReplacement("a"))))
+	if err := cycle.RunArgs([]string{"a"}); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected error for cycle of replacements, got %v", err)
+	}
+
+	mismatch := NewCommand("app", WithFlags(
+		NewFlag("n", "", "…", Integer),
+		NewFlag("old", "", "…", Bool, WithFlagReplacement("n")),
+	))
+	err := mismatch.RunArgs([]string{"--old"})
+	if err == nil || !strings.Contains(err.Error(), "'--old'") || !strings.Contains(err.Error(), "'--n'") {
+		t.Errorf("expected error naming both flags, got %v", err)
+	}
+
+	t.Setenv(NoDeprecationWarningsEnv, "1")
+	buf.Reset()
+
+	if err := c.RunArgs([]string{"ship", "--zone", "eu"}); err != nil || region != "eu" {
+		t.Errorf("expected region 'eu', got '%s' and %v", region, err)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("expected no warnings, got %q", buf.String())
+	}
+}
+
+func TestCommand_aliases(t *testing.T) {
+	var removed []string
+
+	remove := NewCommand("remove",
+		WithCommandAliases("rm", "del"),
+		WithCommandDescription("Removes files"),
+		WithArguments(NewArgument("file", "…", String)),
+		WithAction(func(ctx Context) error {
+			removed = append(removed, ctx.Arguments().String("file"))
+			return nil
+		}),
+	)
+
+	c := NewCommand("app",
+		WithPrefixMatching(true),
+		WithSubcommand(remove),
+		WithSubcommand(NewCommand("rename")),
+	)
+
+	for _, args := range [][]string{{"remove", "a"}, {"rm", "b"}, {"del", "c"}, {"rem", "d"}} {
+		if err := c.RunArgs(args); err != nil {
+			t.Errorf("expected no error for %v, got %v", args, err)
+		}
+	}
+
+	if expected := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(removed, expected) {
+		t.Errorf("expected removed %v, got %v", expected, removed)
+	}
+
+	if err := c.RunArgs([]string{"re", "e"}); err == nil {
+		t.Errorf("expected error for ambiguous subcommand")
+	}
+
+	if n := len(c.Subcommands()); n != 2 {
+		t.Errorf("expected 2 subcommands, got %d", n)
+	}
+
+	if help := c.Help(); !strings.Contains(help, formatBold("remove")+" (rm, del)") {
+		t.Errorf("expected help to list aliases, got:\n%s", help)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for duplicate alias")
+		}
+	}()
+	NewCommand("app", WithSubcommand(remove), WithSubcommand(NewCommand("rm")))
+}
@@ -0,0 +1,160 @@
+package yacli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// ConfigFormat is an interface that defines the decoding of a configuration file.
+//
+// The decoded configuration is a tree of sections keyed by the command path:
+// top-level keys are flags of the root command, nested maps are sections of subcommands.
+//
+//	{
+//	    "verbose": 2,
+//	    "deploy": {"region": "eu", "tags": ["a", "b"]}
+//	}
+//
+// Only JSON is shipped with the package. Other formats, such as TOML or YAML,
+// must be supplied by the caller, e.g. by wrapping a decoding library:
+//
+//	type tomlFormat struct{}
+//
+//	func (tomlFormat) Decode(data []byte) (map[string]any, error) {
+//	    var cfg map[string]any
+//	    err := toml.Unmarshal(data, &cfg)
+//	    return cfg, err
+//	}
+type ConfigFormat interface {
+	// Decode decodes the content of a configuration file.
+	Decode(data []byte) (map[string]any, error)
+}
+
+// JSON is a ConfigFormat which decodes configuration files in JSON format.
+var JSON ConfigFormat = jsonFormat{}
+
+type jsonFormat struct{}
+
+// Decode decodes the content of a JSON configuration file.
+func (jsonFormat) Decode(data []byte) (map[string]any, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var cfg map[string]any
+	if err := d.Decode(&cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// config is a configuration file settings of the root command.
+type config struct {
+	// format is the format of the configuration file.
+	format ConfigFormat
+
+	// paths is a list of default paths of the configuration file.
+	// The first existing file is loaded.
+	paths []string
+
+	// f is the flag which holds the path of the configuration file.
+	f *flag
+}
+
+// WithConfigFile enables loading flag values from a configuration file in the given format.
+//
+// The path of the file is taken from the persistent '--config' flag.
+// If the flag is not passed, the first existing file of the given paths is loaded.
+// Values are converted and validated the same way as command-line values.
+// The precedence is: command line, environment, configuration file, default value.
+// The option takes effect only on the root command.
+func WithConfigFile(format ConfigFormat, paths ...string) commandOption {
+	return func(c *command) {
+		f := &flag{
+			name:        "config",
+			description: "Path to the configuration file",
+			ttype:       String,
+			persistent:  true,
+			noAutoEnv:   true,
+		}
+		c.addFlag(f)
+		c.fg.new(groupDefault).add(f)
+		c.config = &config{format: format, paths: paths, f: f}
+	}
+}
+
+// load reads and decodes the configuration file.
+// It returns the decoded configuration and the path of the file,
+// or nil if no path is passed and none of the default paths exists.
+func (cfg *config) load() (map[string]any, string, error) {
+	if path, ok := cfg.f.value.(string); ok {
+		content, err := cfg.decode(path)
+		return content, path, err
+	}
+
+	for _, path := range cfg.paths {
+		content, err := cfg.decode(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return content, path, err
+	}
+
+	return nil, "", nil
+}
+
+func (cfg *config) decode(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file: %w", err)
+	}
+
+	content, err := cfg.format.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file '%s': %w", path, err)
+	}
+
+	return content, nil
+}
+
+// section returns the section of the configuration which belongs to the command.
+func (c *command) section(content map[string]any) map[string]any {
+	for _, name := range c.subpath() {
+		content, _ = content[name].(map[string]any)
+	}
+	return content
+}
+
+// configValue converts the value of the configuration file to the raw flag value.
+// Scalar values are converted to strings, lists are allowed only for repeatable flags.
+func configValue(v any, repeatable bool) (any, error) {
+	switch vv := v.(type) {
+	case map[string]any:
+		return nil, fmt.Errorf("expected value, got section")
+	case []any:
+		if !repeatable {
+			return nil, fmt.Errorf("expected single value, got list")
+		}
+
+		vs := make([]any, 0, len(vv))
+		for _, v := range vv {
+			s, err := configValue(v, false)
+			if err != nil {
+				return nil, err
+			}
+			vs = append(vs, s)
+		}
+
+		return vs, nil
+	}
+
+	if repeatable {
+		return []any{fmt.Sprint(v)}, nil
+	}
+
+	return fmt.Sprint(v), nil
+}
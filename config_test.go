@@ -0,0 +1,171 @@
+package yacli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCommand_config(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"default.json": `{"verbose": 2, "deploy": {"region": "eu", "tags": ["a", "b"], "replicas": 3}}`,
+		"other.json":   `{"deploy": {"region": "us"}}`,
+		"invalid.json": `{"deploy": {"replicas": "three"}}`,
+		"broken.json":  `{"deploy": `,
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type result struct {
+		region   string
+		tags     []string
+		replicas int
+		timeout  int
+	}
+
+	testCases := []struct {
+		name   string
+		args   []string
+		env    map[string]string
+		want   result
+		hasErr bool
+	}{
+		{
+			name: "default path",
+			args: []string{"deploy"},
+			want: result{region: "eu", tags: []string{"a", "b"}, replicas: 3, timeout: 30},
+		},
+		{
+			name: "config flag",
+			args: []string{"deploy", "--config", filepath.Join(dir, "other.json")},
+			want: result{region: "us", timeout: 30},
+		},
+		{
+			name: "environment over config",
+			args: []string{"deploy"},
+			env:  map[string]string{"APP_DEPLOY_REGION": "asia"},
+			want: result{region: "asia", tags: []string{"a", "b"}, replicas: 3, timeout: 30},
+		},
+		{
+			name: "command line over environment",
+			args: []string{"deploy", "--region", "af", "--replicas=1"},
+			env:  map[string]string{"APP_DEPLOY_REGION": "asia"},
+			want: result{region: "af", tags: []string{"a", "b"}, replicas: 1, timeout: 30},
+		},
+		{
+			name:   "missing config file",
+			args:   []string{"deploy", "--config", filepath.Join(dir, "missing.json")},
+			hasErr: true,
+		},
+		{
+			name:   "invalid value",
+			args:   []string{"deploy", "--config", filepath.Join(dir, "invalid.json")},
+			hasErr: true,
+		},
+		{
+			name:   "broken config file",
+			args:   []string{"deploy", "--config", filepath.Join(dir, "broken.json")},
+			hasErr: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			var got result
+			c := NewCommand(
+				"/opt/My Tools/app",
+				WithEnvPrefix("APP"),
+				WithConfigFile(JSON, filepath.Join(dir, "missing.json"), filepath.Join(dir, "default.json")),
+				WithFlags(NewFlag("verbose", "v", "…", Count)),
+				WithSubcommand(NewCommand(
+					"deploy",
+					WithFlags(
+						NewFlag("region", "r", "…", String),
+						NewFlag("tags", "t", "…", String, WithFlagRepeatable(true)),
+						NewFlag("replicas", "n", "…", Integer),
+						NewFlag("timeout", "w", "…", Integer, WithFlagDefault(30)),
+					),
+					WithAction(func(ctx Context) error {
+						got.region, _ = ctx.Flags().String("region")
+						got.tags, _ = ctx.Flags().Strings("tags")
+						got.replicas, _ = ctx.Flags().Integer("replicas")
+						got.timeout, _ = ctx.Flags().Integer("timeout")
+						return nil
+					}),
+				)),
+			)
+
+			err := c.RunArgs(tt.args)
+			if (err != nil) != tt.hasErr {
+				t.Fatalf("expected error %v, got %v", tt.hasErr, err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCommand_source(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"zone": "b"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_REGION", "eu")
+
+	type source struct {
+		source vsource
+		detail string
+		isSet  bool
+	}
+
+	got := map[string]source{}
+	c := NewCommand(
+		"app",
+		WithConfigFile(JSON, path),
+		WithFlags(
+			NewFlag("verbose", "v", "…", Bool),
+			NewFlag("region", "r", "…", String, WithFlagEnv("APP_REGION")),
+			NewFlag("zone", "z", "…", String),
+			NewFlag("timeout", "t", "…", Integer, WithFlagDefault(30)),
+			NewFlag("name", "n", "…", String),
+		),
+		WithAction(func(ctx Context) error {
+			for _, name := range []string{"verbose", "region", "zone", "timeout", "name", "missing"} {
+				s, detail := ctx.Flags().Source(name)
+				got[name] = source{s, detail, ctx.Flags().IsSet(name)}
+			}
+			return nil
+		}),
+	)
+
+	if err := c.RunArgs([]string{"-v"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := map[string]source{
+		"verbose": {SourceCommandLine, "", true},
+		"region":  {SourceEnv, "APP_REGION", true},
+		"zone":    {SourceConfig, path, true},
+		"timeout": {SourceDefault, "", false},
+		"name":    {SourceNone, "", false},
+		"missing": {SourceNone, "", false},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
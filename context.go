@@ -1,8 +1,13 @@
 package yacli
 
+import stdcontext "context"
+
 // Context is an interface that defines the methods for accessing command-line arguments
 // and flags that were parsed by a command-line parser.
+// It also carries the context.Context passed to the command execution.
 type Context interface {
+	stdcontext.Context
+
 	// Flags returns a flagset object that contains
 	// all the flag values that were parsed.
 	Flags() flagset
@@ -10,6 +15,10 @@ type Context interface {
 	// Arguments returns an argset object that contains
 	// all the positional arguments that were parsed.
 	Arguments() argset
+
+	// Trailing returns the raw arguments that were passed
+	// after the end-of-options terminator (--).
+	Trailing() []string
 }
 
 var _ Context = (*context)(nil)
@@ -17,11 +26,17 @@ var _ Context = (*context)(nil)
 // context represents a parsed command-line context, containing the
 // parsed flags and arguments.
 type context struct {
+	// Context is the context.Context passed to the command execution.
+	stdcontext.Context
+
 	// fs is a flagset containing all the parsed flags.
 	fs flagset
 
 	// as is an argset containing all the parsed arguments.
 	as argset
+
+	// trailing is a slice of raw arguments passed after the terminator.
+	trailing []string
 }
 
 // Flags returns the flagset associated with the context.
@@ -33,3 +48,8 @@ func (c *context) Flags() flagset {
 func (c *context) Arguments() argset {
 	return c.as
 }
+
+// Trailing returns the raw arguments passed after the terminator.
+func (c *context) Trailing() []string {
+	return c.trailing
+}
@@ -0,0 +1,32 @@
+package yacli
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// NoDeprecationWarningsEnv is the environment variable which silences
+// deprecation warnings if it is set to a non-empty value.
+const NoDeprecationWarningsEnv = "YACLI_NO_DEPRECATION_WARNINGS"
+
+// warnings is the writer deprecation warnings are printed to.
+var warnings io.Writer = os.Stderr
+
+// warnDeprecated prints a warning about the use of a deprecated flag or command,
+// e.g. "warning: flag '--old' is deprecated, use '--new' instead: <message>".
+func warnDeprecated(kind, name, replacement, message string) {
+	if os.Getenv(NoDeprecationWarningsEnv) != "" {
+		return
+	}
+
+	warning := fmt.Sprintf("warning: %s '%s' is deprecated", kind, name)
+	if replacement != "" {
+		warning += fmt.Sprintf(", use '%s' instead", replacement)
+	}
+	if message != "" {
+		warning += ": " + message
+	}
+
+	fmt.Fprintln(warnings, warning)
+}
@@ -4,6 +4,58 @@ import "fmt"
 
 type flagOption func(*flag)
 
+// vsource is a source of the flag value.
+type vsource int
+
+const (
+	// SourceNone means that the flag has no value.
+	SourceNone vsource = iota
+
+	// SourceCommandLine means that the flag is passed on the command line.
+	SourceCommandLine
+
+	// SourceEnv means that the value is taken from an environment variable.
+	SourceEnv
+
+	// SourceConfig means that the value is taken from a configuration file.
+	SourceConfig
+
+	// SourceDefault means that the default value of the flag is used.
+	SourceDefault
+)
+
+func (s vsource) String() string {
+	switch s {
+	case SourceCommandLine:
+		return "command line"
+	case SourceEnv:
+		return "environment variable"
+	case SourceConfig:
+		return "config file"
+	case SourceDefault:
+		return "default"
+	default:
+		return "none"
+	}
+}
+
+// negationPrefix is the prefix of the long name which negates a boolean flag.
+const negationPrefix = "no-"
+
+// dpolicy is a policy which defines what happens when a flag is passed more than once.
+type dpolicy int
+
+const (
+	// DuplicateLastWins keeps the value of the last occurrence of the flag.
+	DuplicateLastWins dpolicy = iota
+
+	// DuplicateFirstWins keeps the value of the first occurrence of the flag.
+	DuplicateFirstWins
+
+	// DuplicateError fails if the flag is passed more than once.
+	DuplicateError
+)
+
 type Flag interface {
 	Name() string
 	Short() string
@@ -11,6 +63,13 @@ type Flag interface {
 	Type() ytype
 	Description() string
 	Deprecated() bool
+	Repeatable() bool
+	Negatable() bool
+	Default() any
+	Required() bool
+	Hidden() bool
+	Aliases() []string
+	ShortAliases() []string
 }
 
 var _ Flag = (*flag)(nil)
@@ -18,14 +77,34 @@ var _ Flag = (*flag)(nil)
 type flag struct {
 	name        string
 	short       string
+	aliases     []string
+	shorts      []string
 	description string
 	deprecated  bool
+	message     string
+	replacement string
+	repeatable  bool
+	negatable   bool
+	required    bool
+	persistent  bool
+	hidden      bool
+	envs        []string
+	noAutoEnv   bool
+	optional    bool
+	implicit    string
+	placeholder string
+	policy      dpolicy
 	value       any
+	source      vsource
+	detail      string
+	defvalue    any
 	ttype       ytype
 	cvalidators []func(f Flag) error
+	gvalidators []func(f Flag) error
 }
 
 // NewFlag creates and returns a new Flag instance with the provided name, short name, description, and type.
+// Either the name or the short name can be empty to define a short-only or a long-only flag.
 func NewFlag(name, short, description string, ttype ytype, opts ...flagOption) *flag {
 	f := &flag{
 		name:        name,
@@ -38,6 +117,40 @@ func NewFlag(name, short, description string, ttype ytype, opts ...flagOption) *
 		opt(f)
 	}
 
+	if f.name == "" && f.short == "" {
+		panic(fmt.Errorf("invalid flag: flag '%s' has neither a long nor a short name", f.description))
+	}
+
+	if f.defvalue != nil {
+		v, err := checkDefault(f.ttype, f.repeatable, f.defvalue)
+		if err != nil {
+			panic(fmt.Errorf("invalid flag: flag '%s' has %w", f.name, err))
+		}
+		f.defvalue = v
+	}
+
+	if f.negatable && f.ttype != Bool {
+		panic(fmt.Errorf(
+			"invalid flag: flag '%s' is negatable but its type is %s, not %s", f.key(), f.ttype, Bool,
+		))
+	}
+
+	if f.optional && f.ttype != Count {
+		if _, err := vfuncs[f.ttype](f.implicit); err != nil {
+			panic(fmt.Errorf("invalid flag: flag '%s' has optional value '%s' of incorrect type: %w", f.key(), f.implicit, err))
+		}
+	}
+
+	if f.ttype == Count && (f.repeatable || f.optional) {
+		panic(fmt.Errorf(
+			"invalid flag: flag '%s' of type %s can be neither repeatable nor have an optional value", f.key(), Count,
+		))
+	}
+
+	if f.negatable && f.name == "" {
+		panic(fmt.Errorf("invalid flag: flag '%s' is negatable but has no long name", f.key()))
+	}
+
 	return f
 }
 
@@ -47,6 +160,127 @@ func WithFlagDeprecated(d bool) flagOption {
 	}
 }
 
+// WithFlagDeprecationMessage marks the flag as deprecated with the given message,
+// which is printed to stderr when the flag is passed on the command line.
+func WithFlagDeprecationMessage(msg string) flagOption {
+	return func(f *flag) {
+		f.deprecated = true
+		f.message = msg
+	}
+}
+
+// WithFlagReplacement marks the flag as deprecated in favor of the flag with the given long name.
+// The values passed to the deprecated flag on the command line are forwarded to the replacement,
+// which must be accepted by the same command and have the same type.
+func WithFlagReplacement(name string) flagOption {
+	return func(f *flag) {
+		f.deprecated = true
+		f.replacement = name
+	}
+}
+
+// WithFlagRepeatable makes the flag collect the values of all its occurrences.
+// The values are available as a slice, e.g. with `flagset.Strings`.
+func WithFlagRepeatable(r bool) flagOption {
+	return func(f *flag) {
+		f.repeatable = r
+	}
+}
+
+// WithFlagNegatable registers the '--no-<name>' long form for a boolean flag,
+// which sets the flag to false.
+func WithFlagNegatable(n bool) flagOption {
+	return func(f *flag) {
+		f.negatable = n
+	}
+}
+
+// WithFlagOptionalValue makes the value of the flag optional.
+//
+// If the flag is passed without a value, e.g. '--color', it gets the given value.
+// Another value can be passed only in the same argument, e.g. '--color=never' or '-cnever',
+// the next argument is never consumed as the value of the flag.
+func WithFlagOptionalValue(v string) flagOption {
+	return func(f *flag) {
+		f.optional = true
+		f.implicit = v
+	}
+}
+
+// WithFlagPlaceholder sets the name of the flag value shown in the help message, e.g. 'WHEN'.
+func WithFlagPlaceholder(p string) flagOption {
+	return func(f *flag) {
+		f.placeholder = p
+	}
+}
+
+// WithFlagDuplicates sets the policy which is applied when
+// a non-repeatable flag is passed more than once.
+func WithFlagDuplicates(p dpolicy) flagOption {
+	return func(f *flag) {
+		f.policy = p
+	}
+}
+
+// WithFlagRequired marks the flag as required,
+// the command fails if the flag has no value.
+func WithFlagRequired() flagOption {
+	return func(f *flag) {
+		f.required = true
+	}
+}
+
+// WithFlagAliases adds alternative long names of the flag, e.g. 'dryrun' for 'dry-run'.
+func WithFlagAliases(names ...string) flagOption {
+	return func(f *flag) {
+		f.aliases = append(f.aliases, names...)
+	}
+}
+
+// WithFlagShortAliases adds alternative short names of the flag.
+func WithFlagShortAliases(shorts ...string) flagOption {
+	return func(f *flag) {
+		f.shorts = append(f.shorts, shorts...)
+	}
+}
+
+// WithFlagHidden hides the flag from the usage and help messages,
+// the flag is still accepted on the command line.
+// Hidden flags are shown by the '--help-all' flag.
+func WithFlagHidden() flagOption {
+	return func(f *flag) {
+		f.hidden = true
+	}
+}
+
+// WithFlagEnv binds the flag to the environment variables with the given names.
+// If the flag is not passed, it gets the value of the first non-empty variable.
+// The value is converted to the flag type the same way as the command-line value.
+// A value taken from a variable counts for flags which must be passed together,
+// but not for mutually exclusive flags, which only check the command line.
+func WithFlagEnv(names ...string) flagOption {
+	return func(f *flag) {
+		f.envs = append(f.envs, names...)
+	}
+}
+
+// WithFlagNoAutoEnv excludes the flag from the environment variables
+// derived from the prefix set with WithEnvPrefix.
+func WithFlagNoAutoEnv() flagOption {
+	return func(f *flag) {
+		f.noAutoEnv = true
+	}
+}
+
+// WithFlagDefault sets the value of the flag which is used when the flag is not passed.
+// The value must be of the Go type of the flag type, e.g. int for Integer,
+// or a slice of such values for a repeatable flag.
+func WithFlagDefault(v any) flagOption {
+	return func(f *flag) {
+		f.defvalue = v
+	}
+}
+
 func WithFlagValidator(v func(Flag) error) flagOption {
 	return func(f *flag) {
 		f.cvalidators = append(f.cvalidators, v)
@@ -77,20 +311,102 @@ func (f *flag) Deprecated() bool {
 	return f.deprecated
 }
 
+func (f *flag) Repeatable() bool {
+	return f.repeatable
+}
+
+func (f *flag) Negatable() bool {
+	return f.negatable
+}
+
+func (f *flag) Default() any {
+	return f.defvalue
+}
+
+func (f *flag) Required() bool {
+	return f.required
+}
+
+func (f *flag) Hidden() bool {
+	return f.hidden
+}
+
+func (f *flag) Aliases() []string {
+	return f.aliases
+}
+
+func (f *flag) ShortAliases() []string {
+	return f.shorts
+}
+
 func (f *flag) String() string {
-	return fmt.Sprintf("%s %s", f.Name(), f.Type())
+	return fmt.Sprintf("%s %s", f.key(), f.Type())
 }
 
-func (f *flag) validate() error {
-	if f.value == nil {
-		return nil
+// isSet reports whether the flag got its value from the command line,
+// an environment variable or a configuration file.
+func (f *flag) isSet() bool {
+	return f.source == SourceCommandLine || f.source == SourceEnv || f.source == SourceConfig
+}
+
+// key returns the name the flag is referred to by in messages and in the flagset of the context:
+// the long name or, if the flag is short-only, the short name.
+func (f *flag) key() string {
+	if f.name == "" {
+		return f.short
 	}
+	return f.name
+}
 
-	v, err := vfuncs[f.ttype](f.value)
-	if err != nil {
-		return err
+// option returns the flag as it is passed on the command line, e.g. '--name' or, if the flag is short-only, '-n'.
+func (f *flag) option() string {
+	if f.name == "" {
+		return "-" + f.short
+	}
+	return "--" + f.name
+}
+
+// longNames returns all long names of the flag.
+func (f *flag) longNames() []string {
+	if f.name == "" {
+		return f.aliases
+	}
+	return append([]string{f.name}, f.aliases...)
+}
+
+// shortNames returns all short names of the flag.
+func (f *flag) shortNames() []string {
+	if f.short == "" {
+		return f.shorts
+	}
+	return append([]string{f.short}, f.shorts...)
+}
+
+// validate converts the flag value to the flag type and runs validators against it.
+// If the flag has no value, its default value is used.
+// Group validators are run only for the command-line value.
+func (f *flag) validate() error {
+	switch {
+	case f.source == SourceCommandLine:
+		if err := f.convert(); err != nil {
+			return err
+		}
+
+		for _, gvalidator := range f.gvalidators {
+			if err := gvalidator(f); err != nil {
+				return err
+			}
+		}
+	case f.value != nil:
+	case f.defvalue != nil:
+		f.source = SourceDefault
+		f.value = f.defvalue
+		if vs, ok := f.defvalue.([]any); ok {
+			f.value = append([]any{}, vs...)
+		}
+	default:
+		return nil
 	}
-	f.value = v
 
 	for _, cvalidator := range f.cvalidators {
 		if err := cvalidator(f); err != nil {
@@ -100,3 +416,69 @@ func (f *flag) validate() error {
 
 	return nil
 }
+
+// fill assigns the raw value taken from the given source to the flag which is not passed
+// on the command line and converts it to the flag type.
+// The detail describes the source, e.g. the name of the environment variable.
+func (f *flag) fill(v any, source vsource, detail string) error {
+	f.value, f.source, f.detail = v, source, detail
+	if _, ok := v.([]any); f.repeatable && !ok {
+		f.value = []any{v}
+	}
+
+	if err := f.convert(); err != nil {
+		return fmt.Errorf("invalid flag: flag '%s' from %s '%s': %w", f.key(), source, detail, err)
+	}
+
+	return nil
+}
+
+// convert converts the raw flag value to the flag type.
+func (f *flag) convert() error {
+	if f.repeatable {
+		vs := f.value.([]any)
+		for i := range vs {
+			v, err := vfuncs[f.ttype](vs[i])
+			if err != nil {
+				return err
+			}
+			vs[i] = v
+		}
+	} else {
+		v, err := vfuncs[f.ttype](f.value)
+		if err != nil {
+			return err
+		}
+		f.value = v
+	}
+
+	return nil
+}
+
+// set assigns the value met on the command line to the flag.
+// Counter flags count occurrences unless the count is passed explicitly, e.g. '--verbose=3',
+// repeatable flags accumulate values and others follow the duplicate policy.
+func (f *flag) set(v any) error {
+	f.source = SourceCommandLine
+
+	switch {
+	case f.ttype == Count && v != "":
+		n, err := validateCount(v)
+		if err != nil {
+			return fmt.Errorf("invalid flag: flag '%s' has invalid count '%v': %w", f.key(), v, err)
+		}
+		f.value = n
+	case f.ttype == Count:
+		n, _ := f.value.(int)
+		f.value = n + 1
+	case f.repeatable:
+		vs, _ := f.value.([]any)
+		f.value = append(vs, v)
+	case f.value == nil, f.policy == DuplicateLastWins:
+		f.value = v
+	case f.policy == DuplicateError:
+		return fmt.Errorf("invalid flag: flag '%s' is passed more than once", f.key())
+	}
+
+	return nil
+}
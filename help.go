@@ -2,6 +2,8 @@ package yacli
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"text/template"
 )
 
@@ -15,25 +17,184 @@ var (
 	formatBold = func(msg string) string { return fmt.Sprintf("\033[1m%s\033[0m", msg) }
 )
 
-var helpTemplateRaw = `{{ if .Deprecated }}[{{ FormatRed "DEPRECATED" }}] {{ end }}{{ .Usage }}
+// formatNames returns all short and long names of the flag as they are shown in the help message.
+func formatNames(f *flag) string {
+	var names []string
+	for _, short := range f.shortNames() {
+		names = append(names, formatBold("-"+short))
+	}
+	for _, name := range f.longNames() {
+		names = append(names, formatBold(formatLongName(f, name)))
+	}
+	return strings.Join(names, " | ")
+}
+
+// formatLongName returns the given long name of the flag as it is shown in the help message.
+func formatLongName(f *flag, name string) string {
+	var s strings.Builder
+
+	s.WriteString("--")
+	if f.negatable {
+		s.WriteString(fmt.Sprintf("[%s]", negationPrefix))
+	}
+	s.WriteString(name)
+
+	if f.optional {
+		placeholder := f.placeholder
+		if placeholder == "" {
+			placeholder = f.ttype.String()
+		}
+		s.WriteString(fmt.Sprintf("[=%s]", placeholder))
+	}
+
+	return s.String()
+}
+
+// formatAliases returns the aliases of the command as they are shown in the help message, e.g. ' (rm, del)'.
+func formatAliases(aliases []string) string {
+	if len(aliases) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(aliases, ", "))
+}
+
+// formatDefaultValue returns the default value of a flag or an argument as it is shown in the help message.
+func formatDefaultValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (default: %v)", v)
+}
+
+// formatEnv returns the environment variables bound to the flag as they are shown in the help message.
+func formatEnv(f *flag) string {
+	if len(f.envs) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" [env: %s]", strings.Join(f.envs, ", "))
+}
+
+// view is a view of the command which is shown in the usage and help messages.
+// Hidden flags and subcommands are shown only if all is true.
+type view struct {
+	*command
+	all bool
+}
+
+// visible reports whether the flag or the subcommand with the given hidden mark is shown.
+func (v view) visible(hidden bool) bool {
+	return v.all || !hidden
+}
+
+// Subcommands returns the subcommands of the command shown in the view.
+func (v view) Subcommands() []Command {
+	var subcommands []Command
+	for _, subcommand := range v.cs.commands() {
+		if v.visible(subcommand.hidden) {
+			subcommands = append(subcommands, subcommand)
+		}
+	}
+	return subcommands
+}
+
+// Flags returns the flags of the command shown in the view.
+func (v view) Flags() []Flag {
+	var flags []Flag
+	for _, flag := range v.fs {
+		if v.visible(flag.hidden) {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+// Help returns the help message of the command shown in the view.
+func (v view) Help() string {
+	var s strings.Builder
+	if err := helpTemplate.Execute(&s, v); err != nil {
+		panic(err)
+	}
+	return s.String()
+}
+
+// autoEnv is an environment variable derived for a flag.
+type autoEnv struct {
+	Name string
+	Flag string
+}
+
+// autoEnvs returns the environment variables derived for the shown flags accepted by the command
+// sorted by name. The variable of an inherited flag is derived for the command which declares it.
+func autoEnvs(v view) []autoEnv {
+	scoped := make(map[*flag]bool)
+	for _, f := range v.scoped() {
+		scoped[f] = v.visible(f.hidden)
+	}
+
+	var envs []autoEnv
+	for curr := v.command; curr != nil; curr = curr.parent {
+		for _, f := range curr.fs {
+			if name := curr.autoEnv(f); name != "" && scoped[f] {
+				envs = append(envs, autoEnv{Name: name, Flag: f.name})
+			}
+		}
+	}
+
+	sort.Slice(envs, func(i, j int) bool { return envs[i].Name < envs[j].Name })
+
+	return envs
+}
+
+// globalFlags returns the shown persistent flags inherited by the command sorted by name.
+func globalFlags(v view) []*flag {
+	var flags []*flag
+	for _, f := range v.inherited() {
+		if v.visible(f.hidden) {
+			flags = append(flags, f)
+		}
+	}
+
+	sort.Slice(flags, func(i, j int) bool { return flags[i].name < flags[j].name })
+	return flags
+}
+
+var helpTemplateRaw = `
+{{- define "flag" }}
+    {{ if .Required }}{{ FormatBold "*" | FormatRed }} {{ end }}{{ if .Deprecated }}[{{ FormatRed "DEPRECATED" }}] {{ end }}{{ FormatNames . }} [{{ printf "%s" .Type | FormatBlue }}{{ if .Repeatable }}...{{ end }}] - {{ .Description }}{{ FormatDefaultValue .Default }}{{ FormatEnv . }} 
+{{- end -}}
+{{ if .Deprecated }}[{{ FormatRed "DEPRECATED" }}] {{ end }}{{ .Usage }}
 {{ .Description }}
 
 Flags:
 {{- range .Flags }}
-    {{ if .Deprecated }}[{{ FormatRed "DEPRECATED" }}] {{ end }}{{ printf "-%s" .Short | FormatBold }} | {{printf "--%s" .Name | FormatBold }} [{{ printf "%s" .Type | FormatBlue }}] - {{ .Description }} 
+{{- template "flag" . }}
+{{- end }}
+{{- with GlobalFlags . }}
+
+Global Flags:
+{{- range . }}
+{{- template "flag" . }}
+{{- end }}
 {{- end }}
 {{- if gt (len .Arguments) 0 }} 
 
 Arguments:
 {{- range .Arguments }}
-    {{ if not .Optional}}{{ FormatBold "*" | FormatRed }}{{end}} {{ FormatBold .Name }} [{{ printf "%s" .Type | FormatBlue }}] - {{ .Description }} 
+    {{ if not .Optional}}{{ FormatBold "*" | FormatRed }}{{end}} {{ FormatBold .Name }} [{{ printf "%s" .Type | FormatBlue }}] - {{ .Description }}{{ FormatDefaultValue .Default }} 
+{{- end }}
+{{- end }}
+{{- with AutoEnv . }}
+
+Environment:
+{{- range . }}
+    {{ FormatBold .Name }} - --{{ .Flag }}
 {{- end }}
 {{- end }}
 {{- if gt (len .Subcommands) 0 }}
 
 Subcommands:
 {{- range .Subcommands }}
-    {{ FormatBold .Name }} - {{ .Description }} {{ if .Deprecated }}[{{ FormatRed "DEPRECATED" }}]{{ end }}
+    {{ FormatBold .Name }}{{ FormatAliases .Aliases }} - {{ .Description }} {{ if .Deprecated }}[{{ FormatRed "DEPRECATED" }}]{{ end }}
 {{- end }}
 {{- end }}
 `
@@ -41,10 +202,16 @@ Subcommands:
 var helpTemplate = template.Must(
 	template.New("help").Funcs(
 		map[string]any{
-			"FormatDefault": formatDefault,
-			"FormatRed":     formatRed,
-			"FormatBlue":    formatBlue,
-			"FormatBold":    formatBold,
+			"FormatDefault":      formatDefault,
+			"FormatRed":          formatRed,
+			"FormatBlue":         formatBlue,
+			"FormatBold":         formatBold,
+			"FormatNames":        formatNames,
+			"FormatAliases":      formatAliases,
+			"FormatDefaultValue": formatDefaultValue,
+			"FormatEnv":          formatEnv,
+			"AutoEnv":            autoEnvs,
+			"GlobalFlags":        globalFlags,
 		},
 	).Parse(helpTemplateRaw),
 )
@@ -2,14 +2,38 @@ package yacli
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
+// pmode is a parsing mode which defines how flags and positional arguments can be mixed.
+type pmode int
+
+const (
+	// ParsePermute collects flags from any position of the command line,
+	// so flags and positional arguments can be interleaved (GNU style).
+	ParsePermute pmode = iota
+
+	// ParsePOSIX requires all flags to precede positional arguments
+	// and fails on any flag met after the first positional argument.
+	ParsePOSIX
+)
+
+// entry is a single occurrence of a flag on the command line.
 type entry struct {
+	name   string
 	value  any
 	isLong bool
 }
 
+// scopedEntry is an occurrence of a flag met before the name of a subcommand.
+// It belongs to the ancestor command which was being parsed when the flag was met.
+type scopedEntry struct {
+	entry
+	c *command
+}
+
 // repository is a struct that represents a collection of arguments passed to CLI tool.
 // It contains the positional arguments and flags provided by the user.
 type repository struct {
@@ -17,15 +41,23 @@ type repository struct {
 	// These are the arguments that do not start with a dash (-) or double-dash (--).
 	beforeFlags []string
 
-	// A map that contains the flags and their values.
-	// The keys represent the flag names, and the values represent their values.
+	// A slice that contains the flags and their values in the order they were met.
+	// The same flag can occur several times, so every occurrence is kept.
 	// Flags are the arguments that start with a dash (-) or double-dash (--).
-	flagSet map[string]entry
+	flags []entry
 
 	// A slice of strings representing the positional arguments.
 	// These are the arguments that are not flags, and their order matters.
 	// They are provided after the beforeFlags and the flags.
 	positionalArgs []string
+
+	// A slice of strings representing the raw arguments passed after
+	// the end-of-options terminator (--). They are also appended to positionalArgs.
+	trailingArgs []string
+
+	// A slice that contains the flags met before the names of subcommands, e.g. '-v' in 'app -v deploy'.
+	// Every flag is bound to the ancestor command which was being parsed when the flag was met.
+	ancestorFlags []scopedEntry
 }
 
 // parser represents a command-line argument parser
@@ -37,13 +69,26 @@ type parser struct {
 	osargs []string
 
 	// parsedFlags is a boolean value indicating whether or not the flags
-	// have been parsed yet. If this value is true, then all remaining command-line
-	// arguments are treated as positional arguments and are not parsed as flags.
+	// have been parsed yet. If this value is true and the parser is in ParsePOSIX mode,
+	// then any flag met in the remaining command-line arguments is an error.
 	parsedFlags bool
+
+	// mode is a parsing mode which defines whether flags
+	// can be met after positional arguments or not.
+	mode pmode
+
+	// prefix is a boolean value indicating whether long flags and subcommands
+	// can be referred to by an unambiguous prefix of their names.
+	prefix bool
+
+	// c is the command whose flags are being parsed.
+	// It starts from the root command and descends as subcommand names are met.
+	// If c is nil, every flag is treated as a flag which takes a value.
+	c *command
 }
 
 func newParser(args []string) *parser {
-	return &parser{osargs: args}
+	return &parser{osargs: args, mode: ParsePermute}
 }
 
 // parse is a method of the parser type which parses the
@@ -53,10 +98,16 @@ func newParser(args []string) *parser {
 // encountered during parsing.
 func (p *parser) parse() (repository, error) {
 	beforeFlags, _ := p.parseTillFlags()
+	if p.c != nil {
+		var err error
+		if p.c, beforeFlags, err = p.c.resolve(beforeFlags, p.prefix); err != nil {
+			return repository{}, err
+		}
+	}
 
 	r := repository{
 		beforeFlags:    beforeFlags,
-		flagSet:        make(map[string]entry),
+		flags:          []entry{},
 		positionalArgs: []string{},
 	}
 
@@ -65,8 +116,22 @@ func (p *parser) parse() (repository, error) {
 		arg := p.osargs[i]
 
 		switch {
+		case isTerminator(arg):
+			r.trailingArgs = append([]string{}, p.osargs[i+1:]...)
+			r.positionalArgs = append(r.positionalArgs, r.trailingArgs...)
+			p.parsedFlags = true
+			return r, nil
+		case isNegativeNumber(arg) && p.expectsNumber(len(r.beforeFlags)+len(r.positionalArgs)):
+			if p.isAmbiguous(arg) {
+				return repository{}, fmt.Errorf(
+					"ambiguous argument: '%s' is both a negative number and a flag, use '--' to pass it as an argument", arg,
+				)
+			}
+
+			r.positionalArgs = append(r.positionalArgs, arg)
+			p.parsedFlags = true
 		case isLongFlag(arg):
-			if p.parsedFlags {
+			if p.parsedFlags && p.mode == ParsePOSIX {
 				return repository{}, fmt.Errorf("already parsed flags: invalid flag '%s'", arg)
 			}
 
@@ -83,16 +148,39 @@ func (p *parser) parse() (repository, error) {
 				return repository{}, fmt.Errorf("flag name can not be empty: '%s'", arg)
 			}
 
+			name, err := p.canonical(name)
+			if err != nil {
+				return repository{}, err
+			}
+
+			if f, ok := p.negation(name); ok {
+				if len(parts) == 2 {
+					return repository{}, fmt.Errorf("invalid flag: negated flag '%s' does not take a value", arg)
+				}
+
+				r.flags = append(r.flags, entry{f.name, "false", true})
+				break
+			}
+
 			if len(parts) == 2 {
 				value = parts[1]
-			} else if i+1 < len(p.osargs) && !(isShortFlag(p.osargs[i+1]) || isLongFlag(p.osargs[i+1])) {
-				value = p.osargs[i+1]
-				i++
+			} else if v, ok := p.implicit(name, true); ok {
+				value = v
+			} else if i+1 < len(p.osargs) {
+				ok, err := p.isValue(name, true, p.osargs[i+1])
+				if err != nil {
+					return repository{}, err
+				}
+
+				if ok {
+					value = p.osargs[i+1]
+					i++
+				}
 			}
 
-			r.flagSet[name] = entry{value, true}
+			r.flags = append(r.flags, entry{name, value, true})
 		case isShortFlag(arg):
-			if p.parsedFlags {
+			if p.parsedFlags && p.mode == ParsePOSIX {
 				return repository{}, fmt.Errorf("already parsed flags: invalid flag '%s'", arg)
 			}
 
@@ -101,19 +189,52 @@ func (p *parser) parse() (repository, error) {
 				return repository{}, fmt.Errorf("invalid flag: '%s'", arg)
 			}
 
-			if len(targ) > 1 {
-				for _, l := range targ {
-					r.flagSet[string(l)] = entry{"", false}
+			// Short flags can be clustered: boolean flags are set one by one,
+			// the first flag which takes a value consumes the rest of the cluster
+			// or, if it is the last one in the cluster, the next argument.
+			// Names are sliced by the width of the rune, so an invalid UTF-8 byte
+			// becomes a one-byte name which is reported as an unexpected flag.
+			for j := 0; j < len(targ); {
+				_, width := utf8.DecodeRuneInString(targ[j:])
+				name := targ[j : j+width]
+				rest := targ[j+width:]
+				j += width
+
+				if len(rest) == 0 {
+					value, ok := p.implicit(name, false)
+					if !ok && i+1 < len(p.osargs) {
+						ok, err := p.isValue(name, false, p.osargs[i+1])
+						if err != nil {
+							return repository{}, err
+						}
+
+						if ok {
+							value = p.osargs[i+1]
+							i++
+						}
+					}
+
+					r.flags = append(r.flags, entry{name, value, false})
+					break
+				}
+
+				if f, ok := p.lookup(name, false); ok && (p.takesValue(name, false) || f.optional || strings.HasPrefix(rest, "=")) {
+					r.flags = append(r.flags, entry{name, strings.TrimPrefix(rest, "="), false})
+					break
 				}
-			} else if i+1 < len(p.osargs) && !(isShortFlag(p.osargs[i+1]) || isLongFlag(p.osargs[i+1])) {
-				r.flagSet[targ] = entry{p.osargs[i+1], false}
-				i++
-			} else {
-				r.flagSet[targ] = entry{"", false}
+
+				r.flags = append(r.flags, entry{name, "", false})
 			}
 		default:
-			r.positionalArgs = append(r.positionalArgs, arg)
-			p.parsedFlags = true
+			ok, err := p.descend(&r, arg)
+			if err != nil {
+				return repository{}, err
+			}
+
+			if !ok {
+				r.positionalArgs = append(r.positionalArgs, arg)
+				p.parsedFlags = true
+			}
 		}
 
 		i++
@@ -141,6 +262,156 @@ func (p *parser) parseTillFlags() ([]string, bool) {
 	return beforeFlags, false
 }
 
+// descend steps into the subcommand with the given name if no positional arguments have been met yet.
+// The flags met so far are bound to the command which was being parsed before.
+func (p *parser) descend(r *repository, name string) (bool, error) {
+	if p.c == nil || len(p.c.cs) == 0 || len(r.beforeFlags) > 0 || len(r.positionalArgs) > 0 {
+		return false, nil
+	}
+
+	sc, rest, err := p.c.resolve([]string{name}, p.prefix)
+	if err != nil || len(rest) > 0 {
+		return false, err
+	}
+
+	for _, fentry := range r.flags {
+		r.ancestorFlags = append(r.ancestorFlags, scopedEntry{fentry, p.c})
+	}
+
+	p.c, r.flags = sc, []entry{}
+	return true, nil
+}
+
+// lookup returns the flag of the command being parsed with the given long or short name.
+func (p *parser) lookup(name string, isLong bool) (*flag, bool) {
+	if p.c == nil {
+		return nil, false
+	}
+	return p.c.lookupFlag(name, isLong)
+}
+
+// canonical returns the full name of the long flag which is referred to by the given name.
+// If prefix matching is enabled, the name can be an unambiguous prefix of the flag name.
+// Hidden flags can be referred to only by their full names.
+func (p *parser) canonical(name string) (string, error) {
+	if !p.prefix || p.c == nil {
+		return name, nil
+	}
+
+	fs := p.c.longFlags()
+	if _, ok := fs.get(name); ok {
+		return name, nil
+	}
+
+	candidates := matchPrefix(fs.visible(), name)
+	switch len(candidates) {
+	case 0:
+		return name, nil
+	case 1:
+		return candidates[0], nil
+	}
+
+	return "", fmt.Errorf(
+		"invalid flag: ambiguous flag '--%s' for command '%s', candidates are --%s",
+		name, p.c.name, strings.Join(candidates, ", --"),
+	)
+}
+
+// negation returns the flag which is negated by the given long name, e.g. '--no-color' for '--color'.
+func (p *parser) negation(name string) (*flag, bool) {
+	if p.c == nil {
+		return nil, false
+	}
+	return p.c.lookupNegation(name)
+}
+
+// implicit returns the value of the flag with the given name which is used
+// when the flag is passed without a value, if the flag has an optional value.
+func (p *parser) implicit(name string, isLong bool) (string, bool) {
+	f, ok := p.lookup(name, isLong)
+	if !ok || !f.optional {
+		return "", false
+	}
+	return f.implicit, true
+}
+
+// takesValue reports whether the flag with the given name consumes the next argument as its value.
+// Boolean, counter and optional value flags never consume the next argument,
+// the value of such flags can be passed only as '--flag=value'.
+// Flags which are not known to the parser are considered to take a value.
+func (p *parser) takesValue(name string, isLong bool) bool {
+	f, ok := p.lookup(name, isLong)
+	if !ok {
+		return true
+	}
+
+	return f.ttype != Bool && f.ttype != Count && !f.optional
+}
+
+// isValue reports whether the next argument should be consumed as the value of the flag with the given name.
+// An argument which looks like a flag is consumed only if it is a negative number and the flag is numeric.
+func (p *parser) isValue(name string, isLong bool, next string) (bool, error) {
+	if !p.takesValue(name, isLong) {
+		return false, nil
+	}
+
+	if !(isShortFlag(next) || isLongFlag(next)) {
+		return true, nil
+	}
+
+	f, ok := p.lookup(name, isLong)
+	if !ok || !f.ttype.isNumeric() || !isNegativeNumber(next) {
+		return false, nil
+	}
+
+	if p.isAmbiguous(next) {
+		return false, fmt.Errorf(
+			"ambiguous argument: '%s' is both a negative number and a flag, use '--%s=%s' to pass it as a value",
+			next, f.name, next,
+		)
+	}
+
+	return true, nil
+}
+
+// expectsNumber reports whether the positional argument with the given index has a numeric type.
+func (p *parser) expectsNumber(i int) bool {
+	if p.c == nil || i >= len(p.c.as) {
+		return false
+	}
+	return p.c.as[i].ttype.isNumeric()
+}
+
+// isAmbiguous reports whether the negative number can also be read as a short flag of the command being parsed.
+func (p *parser) isAmbiguous(arg string) bool {
+	_, ok := p.lookup(arg[1:2], false)
+	return ok
+}
+
+// isTerminator is a function that takes a single argument
+// of type string and returns a boolean value indicating
+// whether the given string is the end-of-options terminator (--).
+// Every argument after the terminator is treated as a positional argument.
+func isTerminator(arg string) bool {
+	return arg == "--"
+}
+
+// isNegativeNumber is a function that takes a single argument
+// of type string and returns a boolean value indicating
+// whether the given string is a negative integer or float number.
+func isNegativeNumber(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' || !(isDigit(arg[1]) || arg[1] == '.') {
+		return false
+	}
+
+	_, err := strconv.ParseFloat(arg, 64)
+	return err == nil
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
+
 // isLongFlag is a function that takes a single argument
 // of type string and returns a boolean value indicating
 // whether the given string is a long flag or not.
@@ -16,42 +16,42 @@ func TestParser_long(t *testing.T) {
 			name:  "--key value",
 			flags: []string{"--key", "value"},
 			expected: repository{
-				flagSet: map[string]entry{"key": {"value", true}},
+				flags: []entry{{"key", "value", true}},
 			},
 		},
 		{
 			name:  "--key=value",
 			flags: []string{"--key=value"},
 			expected: repository{
-				flagSet: map[string]entry{"key": {"value", true}},
+				flags: []entry{{"key", "value", true}},
 			},
 		},
 		{
 			name:  "--key='value'",
 			flags: []string{"--key='value'"},
 			expected: repository{
-				flagSet: map[string]entry{"key": {"'value'", true}},
+				flags: []entry{{"key", "'value'", true}},
 			},
 		},
 		{
 			name:  "--key=",
 			flags: []string{"--key="},
 			expected: repository{
-				flagSet: map[string]entry{"key": {"", true}},
+				flags: []entry{{"key", "", true}},
 			},
 		},
 		{
 			name:  "--key ikey=ivalue",
 			flags: []string{"--key", "ikey=ivalue"},
 			expected: repository{
-				flagSet: map[string]entry{"key": {"ikey=ivalue", true}},
+				flags: []entry{{"key", "ikey=ivalue", true}},
 			},
 		},
 		{
 			name:  "--key=ikey=ivalue",
 			flags: []string{"--key=ikey=ivalue"},
 			expected: repository{
-				flagSet: map[string]entry{"key": {"ikey=ivalue", true}},
+				flags: []entry{{"key", "ikey=ivalue", true}},
 			},
 		},
 		{
@@ -60,15 +60,24 @@ func TestParser_long(t *testing.T) {
 			hasErr: true,
 		},
 		{
-			name:   "-- ikey=ivalue",
-			flags:  []string{"--", "ikey=ivalue"},
-			hasErr: true,
+			name:  "-- ikey=ivalue",
+			flags: []string{"--", "ikey=ivalue"},
+			expected: repository{
+				flags: []entry{},
+			},
+		},
+		{
+			name:  "--key avalue --key=bvalue",
+			flags: []string{"--key", "avalue", "--key=bvalue"},
+			expected: repository{
+				flags: []entry{{"key", "avalue", true}, {"key", "bvalue", true}},
+			},
 		},
 		{
 			name:  "--akey --bkey bvalue",
 			flags: []string{"--akey", "--bkey", "value"},
 			expected: repository{
-				flagSet: map[string]entry{"akey": {"", true}, "bkey": {"value", true}},
+				flags: []entry{{"akey", "", true}, {"bkey", "value", true}},
 			},
 		},
 	}
@@ -83,8 +92,8 @@ func TestParser_long(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			if !reflect.DeepEqual(tt.expected.flagSet, r.flagSet) {
-				t.Fatalf("expected %v, got %v", tt.expected.flagSet, r.flagSet)
+			if !reflect.DeepEqual(tt.expected.flags, r.flags) {
+				t.Fatalf("expected %v, got %v", tt.expected.flags, r.flags)
 			}
 		})
 	}
@@ -101,35 +110,42 @@ func TestParser_short(t *testing.T) {
 			name:  "-k value",
 			flags: []string{"-k", "value"},
 			expected: repository{
-				flagSet: map[string]entry{"k": {"value", false}},
+				flags: []entry{{"k", "value", false}},
 			},
 		},
 		{
 			name:  "-abc",
 			flags: []string{"-abc"},
 			expected: repository{
-				flagSet: map[string]entry{"a": {"", false}, "b": {"", false}, "c": {"", false}},
+				flags: []entry{{"a", "", false}, {"b", "", false}, {"c", "", false}},
+			},
+		},
+		{
+			name:  "-aab",
+			flags: []string{"-aab"},
+			expected: repository{
+				flags: []entry{{"a", "", false}, {"a", "", false}, {"b", "", false}},
 			},
 		},
 		{
 			name:  "-a -b -c",
 			flags: []string{"-a", "-b", "-c"},
 			expected: repository{
-				flagSet: map[string]entry{"a": {"", false}, "b": {"", false}, "c": {"", false}},
+				flags: []entry{{"a", "", false}, {"b", "", false}, {"c", "", false}},
 			},
 		},
 		{
 			name:  "-a -b -c value",
 			flags: []string{"-a", "-b", "-c", "value"},
 			expected: repository{
-				flagSet: map[string]entry{"a": {"", false}, "b": {"", false}, "c": {"value", false}},
+				flags: []entry{{"a", "", false}, {"b", "", false}, {"c", "value", false}},
 			},
 		},
 		{
 			name:  "-a value",
 			flags: []string{"-a", "-b", "-c", "value"},
 			expected: repository{
-				flagSet: map[string]entry{"a": {"", false}, "b": {"", false}, "c": {"value", false}},
+				flags: []entry{{"a", "", false}, {"b", "", false}, {"c", "value", false}},
 			},
 		},
 	}
@@ -144,8 +160,8 @@ func TestParser_short(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			if !reflect.DeepEqual(tt.expected.flagSet, r.flagSet) {
-				t.Fatalf("expected %v, got %v", tt.expected.flagSet, r.flagSet)
+			if !reflect.DeepEqual(tt.expected.flags, r.flags) {
+				t.Fatalf("expected %v, got %v", tt.expected.flags, r.flags)
 			}
 		})
 	}
@@ -155,6 +171,7 @@ func TestParser_mixed(t *testing.T) {
 	testCase := []struct {
 		name     string
 		args     []string
+		mode     pmode
 		expected repository
 		hasErr   bool
 	}{
@@ -163,7 +180,7 @@ func TestParser_mixed(t *testing.T) {
 			args: []string{"command", "subcommand", "argument"},
 			expected: repository{
 				beforeFlags:    []string{"command", "subcommand", "argument"},
-				flagSet:        map[string]entry{},
+				flags:          []entry{},
 				positionalArgs: []string{},
 			},
 		},
@@ -172,7 +189,7 @@ func TestParser_mixed(t *testing.T) {
 			args: []string{"command", "subcommand", "--flag", "flag-value", "argument"},
 			expected: repository{
 				beforeFlags:    []string{"command", "subcommand"},
-				flagSet:        map[string]entry{"flag": {"flag-value", true}},
+				flags:          []entry{{"flag", "flag-value", true}},
 				positionalArgs: []string{"argument"},
 			},
 		},
@@ -181,21 +198,52 @@ func TestParser_mixed(t *testing.T) {
 			args: []string{"command", "subcommand", "--flag", "flag-value", "--another-flag=argument"},
 			expected: repository{
 				beforeFlags:    []string{"command", "subcommand"},
-				flagSet:        map[string]entry{"flag": {"flag-value", true}, "another-flag": {"argument", true}},
+				flags:          []entry{{"flag", "flag-value", true}, {"another-flag", "argument", true}},
 				positionalArgs: []string{},
 			},
 		},
 		{
 			name:   "command subcommand --flag flag-value incorrect-argument --another-flag=argument",
 			args:   []string{"command", "subcommand", "--flag", "flag-value", "incorrect-argument", "--another-flag=argument"},
+			mode:   ParsePOSIX,
 			hasErr: true,
 		},
+		{
+			name: "command subcommand --flag flag-value argument --another-flag=argument",
+			args: []string{"command", "subcommand", "--flag", "flag-value", "argument", "--another-flag=argument"},
+			expected: repository{
+				beforeFlags:    []string{"command", "subcommand"},
+				flags:          []entry{{"flag", "flag-value", true}, {"another-flag", "argument", true}},
+				positionalArgs: []string{"argument"},
+			},
+		},
+		{
+			name: "command --flag flag-value -- -rf --weird-name",
+			args: []string{"command", "--flag", "flag-value", "--", "-rf", "--weird-name"},
+			expected: repository{
+				beforeFlags:    []string{"command"},
+				flags:          []entry{{"flag", "flag-value", true}},
+				positionalArgs: []string{"-rf", "--weird-name"},
+				trailingArgs:   []string{"-rf", "--weird-name"},
+			},
+		},
+		{
+			name: "command -- --",
+			args: []string{"command", "--", "--"},
+			expected: repository{
+				beforeFlags:    []string{"command"},
+				flags:          []entry{},
+				positionalArgs: []string{"--"},
+				trailingArgs:   []string{"--"},
+			},
+		},
 	}
 
 	for _, tt := range testCase {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			p := newParser(tt.args)
+			p.mode = tt.mode
 
 			r, err := p.parse()
 			if err != nil && !tt.hasErr {
@@ -206,8 +254,520 @@ func TestParser_mixed(t *testing.T) {
 				t.Fatalf("before flags: expected %v, got %v", tt.expected.beforeFlags, r.beforeFlags)
 			}
 
-			if !reflect.DeepEqual(tt.expected.flagSet, r.flagSet) {
-				t.Fatalf("flag set: expected %v, got %v", tt.expected.flagSet, r.flagSet)
+			if !reflect.DeepEqual(tt.expected.flags, r.flags) {
+				t.Fatalf("flags: expected %v, got %v", tt.expected.flags, r.flags)
+			}
+
+			if !reflect.DeepEqual(tt.expected.positionalArgs, r.positionalArgs) {
+				t.Fatalf("positional args: expected %v, got %v", tt.expected.positionalArgs, r.positionalArgs)
+			}
+
+			if len(tt.expected.trailingArgs) > 0 && !reflect.DeepEqual(tt.expected.trailingArgs, r.trailingArgs) {
+				t.Fatalf("trailing args: expected %v, got %v", tt.expected.trailingArgs, r.trailingArgs)
+			}
+		})
+	}
+}
+
+func TestParser_typed(t *testing.T) {
+	newTypedCommand := func() *command {
+		return NewCommand(
+			"command",
+			WithFlags(
+				NewFlag("verbose", "v", "…", Bool),
+				NewFlag("name", "n", "…", String),
+			),
+			WithSubcommand(NewCommand(
+				"subcommand",
+				WithFlags(NewFlag("force", "f", "…", Bool)),
+			)),
+		)
+	}
+
+	testCase := []struct {
+		name     string
+		args     []string
+		expected repository
+		hasErr   bool
+	}{
+		{
+			name: "--verbose input.txt",
+			args: []string{"--verbose", "input.txt"},
+			expected: repository{
+				flags:          []entry{{"verbose", "", true}},
+				positionalArgs: []string{"input.txt"},
+			},
+		},
+		{
+			name: "-v input.txt",
+			args: []string{"-v", "input.txt"},
+			expected: repository{
+				flags:          []entry{{"v", "", false}},
+				positionalArgs: []string{"input.txt"},
+			},
+		},
+		{
+			name: "--verbose=false input.txt",
+			args: []string{"--verbose=false", "input.txt"},
+			expected: repository{
+				flags:          []entry{{"verbose", "false", true}},
+				positionalArgs: []string{"input.txt"},
+			},
+		},
+		{
+			name: "--name input.txt",
+			args: []string{"--name", "input.txt"},
+			expected: repository{
+				flags:          []entry{{"name", "input.txt", true}},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name: "-n input.txt -v",
+			args: []string{"-n", "input.txt", "-v"},
+			expected: repository{
+				flags:          []entry{{"n", "input.txt", false}, {"v", "", false}},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name: "subcommand --force input.txt",
+			args: []string{"subcommand", "--force", "input.txt"},
+			expected: repository{
+				flags:          []entry{{"force", "", true}},
+				positionalArgs: []string{"input.txt"},
+			},
+		},
+	}
+
+	for _, tt := range testCase {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p := newParser(tt.args)
+			p.c = newTypedCommand()
+
+			r, err := p.parse()
+			if err != nil && !tt.hasErr {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tt.expected.flags, r.flags) {
+				t.Fatalf("flags: expected %v, got %v", tt.expected.flags, r.flags)
+			}
+
+			if !reflect.DeepEqual(tt.expected.positionalArgs, r.positionalArgs) {
+				t.Fatalf("positional args: expected %v, got %v", tt.expected.positionalArgs, r.positionalArgs)
+			}
+		})
+	}
+}
+
+func TestParser_numeric(t *testing.T) {
+	newNumericCommand := func() *command {
+		return NewCommand(
+			"calc",
+			WithFlags(
+				NewFlag("offset", "o", "…", Integer),
+				NewFlag("ratio", "r", "…", Float64),
+				NewFlag("name", "n", "…", String),
+				NewFlag("nine", "9", "…", Bool),
+			),
+			WithArguments(
+				NewArgument("x", "…", Integer),
+				NewArgument("y", "…", Float64),
+			),
+		)
+	}
+
+	testCase := []struct {
+		name     string
+		args     []string
+		expected repository
+		hasErr   bool
+	}{
+		{
+			name: "-5 3",
+			args: []string{"-5", "3"},
+			expected: repository{
+				flags:          []entry{},
+				positionalArgs: []string{"-5", "3"},
+			},
+		},
+		{
+			name: "5 -3.5",
+			args: []string{"5", "-3.5"},
+			expected: repository{
+				beforeFlags:    []string{"5"},
+				flags:          []entry{},
+				positionalArgs: []string{"-3.5"},
+			},
+		},
+		{
+			name: "--offset -10 -1",
+			args: []string{"--offset", "-10", "-1"},
+			expected: repository{
+				flags:          []entry{{"offset", "-10", true}},
+				positionalArgs: []string{"-1"},
+			},
+		},
+		{
+			name: "-r -.5",
+			args: []string{"-r", "-.5"},
+			expected: repository{
+				flags:          []entry{{"r", "-.5", false}},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name: "--name -1",
+			args: []string{"--name", "-1"},
+			expected: repository{
+				flags:          []entry{{"name", "", true}},
+				positionalArgs: []string{"-1"},
+			},
+		},
+		{
+			name:   "-o -9",
+			args:   []string{"-o", "-9"},
+			hasErr: true,
+		},
+		{
+			name:   "-9",
+			args:   []string{"-9"},
+			hasErr: true,
+		},
+		{
+			name: "-1 -2 -3",
+			args: []string{"-1", "-2", "-3"},
+			expected: repository{
+				flags:          []entry{{"3", "", false}},
+				positionalArgs: []string{"-1", "-2"},
+			},
+		},
+	}
+
+	for _, tt := range testCase {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p := newParser(tt.args)
+			p.c = newNumericCommand()
+
+			r, err := p.parse()
+			if err != nil && !tt.hasErr {
+				t.Fatal(err)
+			}
+
+			if err == nil && tt.hasErr {
+				t.Fatal("expected error, got nil")
+			}
+
+			if !reflect.DeepEqual(tt.expected.beforeFlags, r.beforeFlags) {
+				t.Fatalf("before flags: expected %v, got %v", tt.expected.beforeFlags, r.beforeFlags)
+			}
+
+			if !reflect.DeepEqual(tt.expected.flags, r.flags) {
+				t.Fatalf("flags: expected %v, got %v", tt.expected.flags, r.flags)
+			}
+
+			if !reflect.DeepEqual(tt.expected.positionalArgs, r.positionalArgs) {
+				t.Fatalf("positional args: expected %v, got %v", tt.expected.positionalArgs, r.positionalArgs)
+			}
+		})
+	}
+}
+
+func TestParser_cluster(t *testing.T) {
+	newClusterCommand := func() *command {
+		return NewCommand(
+			"command",
+			WithFlags(
+				NewFlag("verbose", "v", "…", Bool),
+				NewFlag("force", "f", "…", Bool),
+				NewFlag("amount", "n", "…", Integer),
+			),
+		)
+	}
+
+	testCase := []struct {
+		name     string
+		args     []string
+		expected repository
+	}{
+		{
+			name: "-n10",
+			args: []string{"-n10"},
+			expected: repository{
+				flags:          []entry{{"n", "10", false}},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name: "-n=10",
+			args: []string{"-n=10"},
+			expected: repository{
+				flags:          []entry{{"n", "10", false}},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name: "-n-10",
+			args: []string{"-n-10"},
+			expected: repository{
+				flags:          []entry{{"n", "-10", false}},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name: "-v\\xff",
+			args: []string{"-v\xff"},
+			expected: repository{
+				flags:          []entry{{"v", "", false}, {"\xff", "", false}},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name: "-vn 10",
+			args: []string{"-vn", "10"},
+			expected: repository{
+				flags:          []entry{{"v", "", false}, {"n", "10", false}},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name: "-vfn10 argument",
+			args: []string{"-vfn10", "argument"},
+			expected: repository{
+				flags:          []entry{{"v", "", false}, {"f", "", false}, {"n", "10", false}},
+				positionalArgs: []string{"argument"},
+			},
+		},
+		{
+			name: "-nv",
+			args: []string{"-nv"},
+			expected: repository{
+				flags:          []entry{{"n", "v", false}},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name: "-fv argument",
+			args: []string{"-fv", "argument"},
+			expected: repository{
+				flags:          []entry{{"f", "", false}, {"v", "", false}},
+				positionalArgs: []string{"argument"},
+			},
+		},
+		{
+			name: "-v=false",
+			args: []string{"-v=false"},
+			expected: repository{
+				flags:          []entry{{"v", "false", false}},
+				positionalArgs: []string{},
+			},
+		},
+	}
+
+	for _, tt := range testCase {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p := newParser(tt.args)
+			p.c = newClusterCommand()
+
+			r, err := p.parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tt.expected.flags, r.flags) {
+				t.Fatalf("flags: expected %v, got %v", tt.expected.flags, r.flags)
+			}
+
+			if !reflect.DeepEqual(tt.expected.positionalArgs, r.positionalArgs) {
+				t.Fatalf("positional args: expected %v, got %v", tt.expected.positionalArgs, r.positionalArgs)
+			}
+		})
+	}
+}
+
+func TestParser_prefix(t *testing.T) {
+	newPrefixCommand := func() *command {
+		return NewCommand(
+			"command",
+			WithFlags(
+				NewFlag("verbose", "v", "…", Bool),
+				NewFlag("version", "V", "…", Bool),
+				NewFlag("dry", "d", "…", Bool),
+				NewFlag("dry-run", "r", "…", Bool),
+				NewFlag("name", "n", "…", String),
+			),
+			WithSubcommand(NewCommand("status")),
+			WithSubcommand(NewCommand("stash")),
+			WithSubcommand(NewCommand("start")),
+		)
+	}
+
+	testCase := []struct {
+		name     string
+		args     []string
+		command  string
+		expected repository
+		hasErr   bool
+	}{
+		{
+			name:    "--verb --na value",
+			args:    []string{"--verb", "--na", "value"},
+			command: "command",
+			expected: repository{
+				flags:          []entry{{"verbose", "", true}, {"name", "value", true}},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name:    "--dry",
+			args:    []string{"--dry"},
+			command: "command",
+			expected: repository{
+				flags:          []entry{{"dry", "", true}},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name:    "--dry-",
+			args:    []string{"--dry-"},
+			command: "command",
+			expected: repository{
+				flags:          []entry{{"dry-run", "", true}},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name:   "--ver",
+			args:   []string{"--ver"},
+			hasErr: true,
+		},
+		{
+			name:    "stat argument",
+			args:    []string{"stat", "argument"},
+			command: "status",
+			expected: repository{
+				beforeFlags:    []string{"argument"},
+				flags:          []entry{},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name:   "sta",
+			args:   []string{"sta"},
+			hasErr: true,
+		},
+	}
+
+	for _, tt := range testCase {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p := newParser(tt.args)
+			p.c = newPrefixCommand()
+			p.prefix = true
+
+			r, err := p.parse()
+			if (err != nil) != tt.hasErr {
+				t.Fatalf("expected error %v, got %v", tt.hasErr, err)
+			}
+
+			if tt.hasErr {
+				return
+			}
+
+			if p.c.name != tt.command {
+				t.Fatalf("command: expected %s, got %s", tt.command, p.c.name)
+			}
+
+			if !reflect.DeepEqual(tt.expected.beforeFlags, r.beforeFlags) {
+				t.Fatalf("before flags: expected %v, got %v", tt.expected.beforeFlags, r.beforeFlags)
+			}
+
+			if !reflect.DeepEqual(tt.expected.flags, r.flags) {
+				t.Fatalf("flags: expected %v, got %v", tt.expected.flags, r.flags)
+			}
+
+			if !reflect.DeepEqual(tt.expected.positionalArgs, r.positionalArgs) {
+				t.Fatalf("positional args: expected %v, got %v", tt.expected.positionalArgs, r.positionalArgs)
+			}
+		})
+	}
+}
+
+func TestParser_optional(t *testing.T) {
+	newOptionalCommand := func() *command {
+		return NewCommand(
+			"command",
+			WithFlags(
+				NewFlag("verbose", "v", "…", Bool),
+				NewFlag("color", "c", "…", String, WithFlagOptionalValue("auto")),
+			),
+		)
+	}
+
+	testCase := []struct {
+		name     string
+		args     []string
+		expected repository
+	}{
+		{
+			name: "--color input",
+			args: []string{"--color", "input"},
+			expected: repository{
+				flags:          []entry{{"color", "auto", true}},
+				positionalArgs: []string{"input"},
+			},
+		},
+		{
+			name: "--color=never input",
+			args: []string{"--color=never", "input"},
+			expected: repository{
+				flags:          []entry{{"color", "never", true}},
+				positionalArgs: []string{"input"},
+			},
+		},
+		{
+			name: "-c input",
+			args: []string{"-c", "input"},
+			expected: repository{
+				flags:          []entry{{"c", "auto", false}},
+				positionalArgs: []string{"input"},
+			},
+		},
+		{
+			name: "-cnever -c=always",
+			args: []string{"-cnever", "-c=always"},
+			expected: repository{
+				flags:          []entry{{"c", "never", false}, {"c", "always", false}},
+				positionalArgs: []string{},
+			},
+		},
+		{
+			name: "-vc input",
+			args: []string{"-vc", "input"},
+			expected: repository{
+				flags:          []entry{{"v", "", false}, {"c", "auto", false}},
+				positionalArgs: []string{"input"},
+			},
+		},
+	}
+
+	for _, tt := range testCase {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p := newParser(tt.args)
+			p.c = newOptionalCommand()
+
+			r, err := p.parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tt.expected.flags, r.flags) {
+				t.Fatalf("flags: expected %v, got %v", tt.expected.flags, r.flags)
 			}
 
 			if !reflect.DeepEqual(tt.expected.positionalArgs, r.positionalArgs) {
@@ -215,4 +775,4 @@ func TestParser_mixed(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
@@ -6,19 +6,22 @@ import (
 )
 
 func (c *command) Usage() string {
+	return view{command: c}.Usage()
+}
+
+// Usage returns the usage message of the command shown in the view.
+func (v view) Usage() string {
 	var s strings.Builder
 
-	s.WriteString(c.Name())
+	s.WriteString(v.Name())
 
-	if len(c.cs) > 0 {
+	if subcommands := v.Subcommands(); len(subcommands) > 0 {
 		s.WriteString(" [")
-		var i int
-		for name := range c.cs {
-			s.WriteString(fmt.Sprintf(" %s", name))
-			if i < len(c.cs)-1 {
+		for i, subcommand := range subcommands {
+			s.WriteString(fmt.Sprintf(" %s", subcommand.Name()))
+			if i < len(subcommands)-1 {
 				s.WriteString(" |")
 			}
-			i++
 		}
 		s.WriteString(" ]")
 	}
@@ -29,14 +32,21 @@ func (c *command) Usage() string {
 		togetherGroup []*flag
 	)
 
-	for _, g := range c.fg {
+	for _, g := range v.fg {
+		var flags []*flag
+		for _, f := range g.flags {
+			if v.visible(f.hidden) {
+				flags = append(flags, f)
+			}
+		}
+
 		switch g.ttype {
 		case groupDefault:
-			defaultGroup = append(defaultGroup, g.flags...)
+			defaultGroup = append(defaultGroup, flags...)
 		case groupMutex:
-			mutexGroup = append(mutexGroup, g.flags...)
+			mutexGroup = append(mutexGroup, flags...)
 		case groupTogether:
-			togetherGroup = append(togetherGroup, g.flags...)
+			togetherGroup = append(togetherGroup, flags...)
 		}
 	}
 
@@ -52,7 +62,7 @@ func (c *command) Usage() string {
 		s.WriteString(formatTogetherGroup(togetherGroup...))
 	}
 
-	for _, arg := range c.as {
+	for _, arg := range v.as {
 		s.WriteString(fmt.Sprintf(" %s", arg.Name()))
 	}
 
@@ -62,8 +72,12 @@ func (c *command) Usage() string {
 func formatDefaultGroup(flags ...*flag) string {
 	var s strings.Builder
 	for _, f := range flags {
+		if f.Required() {
+			s.WriteString(fmt.Sprintf(" %s", formatUsageName(f)))
+			continue
+		}
 		s.WriteString(" [")
-		s.WriteString(fmt.Sprintf(" -%s", f.Short()))
+		s.WriteString(fmt.Sprintf(" %s", formatUsageName(f)))
 		s.WriteString(" ]")
 	}
 	return s.String()
@@ -73,7 +87,7 @@ func formatMutexGroup(flags ...*flag) string {
 	var s strings.Builder
 	s.WriteString(" [")
 	for i, f := range flags {
-		s.WriteString(fmt.Sprintf(" -%s", f.Short()))
+		s.WriteString(fmt.Sprintf(" %s", formatUsageName(f)))
 		if i < len(flags)-1 {
 			s.WriteString(" |")
 		}
@@ -86,8 +100,17 @@ func formatTogetherGroup(flags ...*flag) string {
 	var s strings.Builder
 	s.WriteString(" [")
 	for _, f := range flags {
-		s.WriteString(fmt.Sprintf(" -%s", f.Short()))
+		s.WriteString(fmt.Sprintf(" %s", formatUsageName(f)))
 	}
 	s.WriteString(" ]")
 	return s.String()
 }
+
+// formatUsageName returns the name of the flag shown in the usage message:
+// the short name or, if the flag is long-only, the long name.
+func formatUsageName(f *flag) string {
+	if f.short == "" {
+		return "--" + f.name
+	}
+	return "-" + f.short
+}
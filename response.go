@@ -0,0 +1,86 @@
+package yacli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// expander expands response files (@file) in the command-line arguments.
+type expander struct {
+	// stack is a slice of absolute paths of the response files being expanded.
+	// It is used to detect response files which include themselves.
+	stack []string
+
+	// terminated is a boolean value indicating whether the end-of-options
+	// terminator (--) was met. Arguments after it are not expanded.
+	terminated bool
+}
+
+// expandResponseFiles replaces every '@path' argument with the words read from the file at path.
+//
+// The file is split into words the way a shell does, see splitWords.
+// Response files can include other response files, relative paths inside a response file
+// are resolved against the directory of that file. '@@literal' is replaced with '@literal'.
+func expandResponseFiles(args []string) ([]string, error) {
+	e := &expander{}
+	return e.expand(args, "")
+}
+
+func (e *expander) expand(args []string, dir string) ([]string, error) {
+	expanded := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		switch {
+		case e.terminated || len(arg) < 2 || arg[0] != '@':
+			e.terminated = e.terminated || isTerminator(arg)
+			expanded = append(expanded, arg)
+		case arg[1] == '@':
+			expanded = append(expanded, arg[1:])
+		default:
+			words, err := e.read(arg[1:], dir)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, words...)
+		}
+	}
+
+	return expanded, nil
+}
+
+func (e *expander) read(path, dir string) ([]string, error) {
+	if !filepath.IsAbs(path) && dir != "" {
+		path = filepath.Join(dir, path)
+	}
+
+	apath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range e.stack {
+		if p == apath {
+			return nil, fmt.Errorf(
+				"invalid response file: cycle detected %s -> %s",
+				strings.Join(e.stack, " -> "), apath,
+			)
+		}
+	}
+
+	content, err := os.ReadFile(apath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response file: %w", err)
+	}
+
+	words, err := splitWords(string(content), true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response file '%s': %w", path, err)
+	}
+
+	e.stack = append(e.stack, apath)
+	defer func() { e.stack = e.stack[:len(e.stack)-1] }()
+
+	return e.expand(words, filepath.Dir(apath))
+}
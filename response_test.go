@@ -0,0 +1,93 @@
+package yacli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandResponseFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"flags":      "--amount 10 # amount of repetitions\n--separator ' | '\n",
+		"nested":     "-v @inner/args",
+		"inner/args": "@../flags 'inner argument'",
+		"cycle":      "@cycle-back",
+		"cycle-back": "@cycle",
+		"broken":     "'unterminated",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	at := func(name string) string { return "@" + filepath.Join(dir, name) }
+
+	tests := []struct {
+		name   string
+		args   []string
+		want   []string
+		hasErr bool
+	}{
+		{
+			name: "without response files",
+			args: []string{"sum", "-n", "10", "@"},
+			want: []string{"sum", "-n", "10", "@"},
+		},
+		{
+			name: "response file",
+			args: []string{"echo", at("flags"), "message"},
+			want: []string{"echo", "--amount", "10", "--separator", " | ", "message"},
+		},
+		{
+			name: "nested response files",
+			args: []string{at("nested")},
+			want: []string{"-v", "--amount", "10", "--separator", " | ", "inner argument"},
+		},
+		{
+			name: "escaped at sign",
+			args: []string{"@@literal"},
+			want: []string{"@literal"},
+		},
+		{
+			name: "after terminator",
+			args: []string{"--", at("flags")},
+			want: []string{"--", at("flags")},
+		},
+		{
+			name:   "cycle",
+			args:   []string{at("cycle")},
+			hasErr: true,
+		},
+		{
+			name:   "missing file",
+			args:   []string{at("missing")},
+			hasErr: true,
+		},
+		{
+			name:   "broken file",
+			args:   []string{at("broken")},
+			hasErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandResponseFiles(tt.args)
+			if (err != nil) != tt.hasErr {
+				t.Fatalf("expandResponseFiles(%q)=%q error=%v, wantErr %v", tt.args, got, err, tt.hasErr)
+			}
+
+			if !tt.hasErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandResponseFiles(%q)=%q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,10 @@
 package yacli
 
+import (
+	"sort"
+	"strings"
+)
+
 // commandset is a set of commands mapped by their name.
 type commandset map[string]*command
 
@@ -23,6 +28,32 @@ func (cs commandset) get(name string) (*command, bool) {
 	return c, ok
 }
 
+// commands returns the distinct commands of the command set sorted by name.
+// A command registered under several aliases is returned once.
+func (cs commandset) commands() []*command {
+	var commands []*command
+	for name, c := range cs {
+		if name == c.name {
+			commands = append(commands, c)
+		}
+	}
+
+	sort.Slice(commands, func(i, j int) bool { return commands[i].name < commands[j].name })
+
+	return commands
+}
+
+// visible returns the commands of the command set which are not hidden.
+func (cs commandset) visible() commandset {
+	vcs := make(commandset, len(cs))
+	for name, c := range cs {
+		if !c.hidden {
+			vcs.set(name, c)
+		}
+	}
+	return vcs
+}
+
 // flagset is a map of string flag names to pointers to flag objects.
 // It is used to store and retrieve flag values by name.
 type flagset map[string]*flag
@@ -44,6 +75,17 @@ func (fs flagset) get(name string) (*flag, bool) {
 	return f, ok
 }
 
+// visible returns the flags of the flagset which are not hidden.
+func (fs flagset) visible() flagset {
+	vfs := make(flagset, len(fs))
+	for name, f := range fs {
+		if !f.hidden {
+			vfs.set(name, f)
+		}
+	}
+	return vfs
+}
+
 // Integer retrieves the value of an integer flag.
 // If the flag is not found, the second return value is false.
 func (fs flagset) Integer(name string) (int, bool) {
@@ -135,6 +177,114 @@ func (fs flagset) Bool(name string) (bool, bool) {
 	return v, ok
 }
 
+// Count retrieves the number of occurrences of a counter flag.
+// If the flag is not found, the second return value is false.
+func (fs flagset) Count(name string) (int, bool) {
+	f, ok := fs.get(name)
+	if !ok {
+		return 0, false
+	}
+	v, ok := f.value.(int)
+	return v, ok
+}
+
+// IsSet reports whether the flag got its value from the command line,
+// an environment variable or a configuration file. A default value does not count.
+func (fs flagset) IsSet(name string) bool {
+	f, ok := fs.get(name)
+	return ok && f.isSet()
+}
+
+// Source retrieves the source of the flag value and its detail:
+// the name of the environment variable or the path of the configuration file.
+// If the flag is not found or has no value, SourceNone is returned.
+func (fs flagset) Source(name string) (vsource, string) {
+	f, ok := fs.get(name)
+	if !ok {
+		return SourceNone, ""
+	}
+	return f.source, f.detail
+}
+
+// Integers retrieves the values of a repeatable integer flag.
+// If the flag is not found, the second return value is false.
+func (fs flagset) Integers(name string) ([]int, bool) {
+	return flagValues[int](fs, name)
+}
+
+// Integer8s retrieves the values of a repeatable int8 flag.
+// If the flag is not found, the second return value is false.
+func (fs flagset) Integer8s(name string) ([]int8, bool) {
+	return flagValues[int8](fs, name)
+}
+
+// Integer16s retrieves the values of a repeatable int16 flag.
+// If the flag is not found, the second return value is false.
+func (fs flagset) Integer16s(name string) ([]int16, bool) {
+	return flagValues[int16](fs, name)
+}
+
+// Integer32s retrieves the values of a repeatable int32 flag.
+// If the flag is not found, the second return value is false.
+func (fs flagset) Integer32s(name string) ([]int32, bool) {
+	return flagValues[int32](fs, name)
+}
+
+// Integer64s retrieves the values of a repeatable int64 flag.
+// If the flag is not found, the second return value is false.
+func (fs flagset) Integer64s(name string) ([]int64, bool) {
+	return flagValues[int64](fs, name)
+}
+
+// Float32s retrieves the values of a repeatable float32 flag.
+// If the flag is not found, the second return value is false.
+func (fs flagset) Float32s(name string) ([]float32, bool) {
+	return flagValues[float32](fs, name)
+}
+
+// Float64s retrieves the values of a repeatable float64 flag.
+// If the flag is not found, the second return value is false.
+func (fs flagset) Float64s(name string) ([]float64, bool) {
+	return flagValues[float64](fs, name)
+}
+
+// Strings retrieves the values of a repeatable string flag.
+// If the flag is not found, the second return value is false.
+func (fs flagset) Strings(name string) ([]string, bool) {
+	return flagValues[string](fs, name)
+}
+
+// Bools retrieves the values of a repeatable boolean flag.
+// If the flag is not found, the second return value is false.
+func (fs flagset) Bools(name string) ([]bool, bool) {
+	return flagValues[bool](fs, name)
+}
+
+// flagValues retrieves the values of a repeatable flag as a slice of type T.
+// If the flag is not found or its values have different type, the second return value is false.
+func flagValues[T any](fs flagset, name string) ([]T, bool) {
+	f, ok := fs.get(name)
+	if !ok {
+		return nil, false
+	}
+
+	vs, ok := f.value.([]any)
+	if !ok {
+		return nil, false
+	}
+
+	ts := make([]T, 0, len(vs))
+	for _, v := range vs {
+		t, ok := v.(T)
+		if !ok {
+			return nil, false
+		}
+		ts = append(ts, t)
+	}
+
+	return ts, true
+}
+
 // argset is a slice of pointers to argument objects.
 type argset []*argument
 
@@ -206,3 +356,32 @@ func (as argset) String(name string) string {
 func (as argset) Bool(name string) bool {
 	return as.get(name).value.(bool)
 }
+
+// matchPrefix returns the keys of the set which match the given name.
+// If the set contains the name itself, only the name is returned,
+// otherwise all keys which start with the name are returned in sorted order.
+// Keys of the same value, e.g. aliases of a command, are returned once.
+func matchPrefix[V comparable](set map[string]V, name string) []string {
+	if _, ok := set[name]; ok {
+		return []string{name}
+	}
+
+	var keys []string
+	for key := range set {
+		if strings.HasPrefix(key, name) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var candidates []string
+	seen := make(map[V]bool)
+	for _, key := range keys {
+		if !seen[set[key]] {
+			seen[set[key]] = true
+			candidates = append(candidates, key)
+		}
+	}
+
+	return candidates
+}
@@ -48,6 +48,18 @@ func TestFlagSet(t *testing.T) {
 		t.Error("expected String('missing') to return false")
 	}
 
+	// Test repeatable flags
+	fs.set("strings", &flag{value: []any{"a", "b"}})
+	if v, ok := fs.Strings("strings"); !ok || len(v) != 2 || v[0] != "a" || v[1] != "b" {
+		t.Errorf("expected Strings('strings') to return [a b], got %v (ok=%v)", v, ok)
+	}
+	if _, ok := fs.Integers("strings"); ok {
+		t.Error("expected Integers('strings') to return false")
+	}
+	if _, ok := fs.Strings("string"); ok {
+		t.Error("expected Strings('string') to return false")
+	}
+
 	// Test Bool
 	if v, ok := fs.Bool("bool"); !ok || !v {
 		t.Errorf("expected Bool('bool') to return true, got %v (ok=%v)", v, ok)
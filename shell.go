@@ -0,0 +1,98 @@
+package yacli
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// splitWords splits the given string into words the way a POSIX shell does.
+//
+// Words are separated by whitespace. Single quotes preserve every character literally,
+// double quotes allow backslash to escape '"', '\', '$', '`' and a newline.
+// A backslash outside of quotes escapes the next character, a backslash before a newline
+// joins the lines. If comments is true, a '#' at the beginning of a word starts a comment
+// till the end of the line.
+// It returns an error if a quote is not terminated or the string ends with a backslash.
+func splitWords(s string, comments bool) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		inWord bool
+	)
+
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case r == '#' && !inWord && comments:
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		case r == '\\':
+			if i+1 >= len(rs) {
+				return nil, fmt.Errorf("invalid syntax: unexpected end of input after '\\'")
+			}
+
+			i++
+			if rs[i] != '\n' {
+				word.WriteRune(rs[i])
+				inWord = true
+			}
+		case r == '\'':
+			j := i + 1
+			for j < len(rs) && rs[j] != '\'' {
+				j++
+			}
+
+			if j >= len(rs) {
+				return nil, fmt.Errorf("invalid syntax: unterminated single quote")
+			}
+
+			word.WriteString(string(rs[i+1 : j]))
+			inWord = true
+			i = j
+		case r == '"':
+			j := i + 1
+			for ; j < len(rs) && rs[j] != '"'; j++ {
+				if rs[j] != '\\' || j+1 >= len(rs) {
+					word.WriteRune(rs[j])
+					continue
+				}
+
+				switch rs[j+1] {
+				case '"', '\\', '$', '`':
+					word.WriteRune(rs[j+1])
+				case '\n':
+				default:
+					word.WriteRune(rs[j])
+					word.WriteRune(rs[j+1])
+				}
+				j++
+			}
+
+			if j >= len(rs) {
+				return nil, fmt.Errorf("invalid syntax: unterminated double quote")
+			}
+
+			inWord = true
+			i = j
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
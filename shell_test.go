@@ -0,0 +1,42 @@
+package yacli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		comments bool
+		want     []string
+		hasErr   bool
+	}{
+		{"empty", "", false, nil, false},
+		{"spaces", "  a   b\tc\n", false, []string{"a", "b", "c"}, false},
+		{"single quotes", `'a b' 'c"d'`, false, []string{"a b", `c"d`}, false},
+		{"double quotes", `"a b" "c'd" "e\"f" "g\h"`, false, []string{"a b", "c'd", `e"f`, `g\h`}, false},
+		{"empty quotes", `a "" ''`, false, []string{"a", "", ""}, false},
+		{"adjacent quotes", `--name="a b"'c'`, false, []string{"--name=a bc"}, false},
+		{"escapes", `a\ b \'c\"`, false, []string{"a b", `'c"`}, false},
+		{"line continuation", "a\\\nb", false, []string{"ab"}, false},
+		{"comments", "a # comment\n# another comment\nb#c", true, []string{"a", "b#c"}, false},
+		{"comments disabled", "fix #123", false, []string{"fix", "#123"}, false},
+		{"unterminated single quote", `a 'b`, false, nil, true},
+		{"unterminated double quote", `a "b\"`, false, nil, true},
+		{"trailing backslash", `a \`, false, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitWords(tt.s, tt.comments)
+			if (err != nil) != tt.hasErr {
+				t.Fatalf("splitWords(%q)=%q error=%v, wantErr %v", tt.s, got, err, tt.hasErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitWords(%q)=%q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
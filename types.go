@@ -1,6 +1,7 @@
 package yacli
 
 import (
+	"fmt"
 	"reflect"
 	"strconv"
 
@@ -19,11 +20,23 @@ func (y ytype) String() string {
 		return "STRING"
 	case Bool:
 		return "BOOL"
+	case Count:
+		return "COUNT"
 	default:
 		return "UNKNOWN"
 	}
 }
 
+// isNumeric reports whether the type represents an integer or float number.
+func (y ytype) isNumeric() bool {
+	switch y {
+	case Integer, Integer8, Integer16, Integer32, Integer64, Float32, Float64:
+		return true
+	default:
+		return false
+	}
+}
+
 const (
 	Integer ytype = iota
 	Integer8
@@ -34,8 +47,48 @@ const (
 	Float64
 	String
 	Bool
+	Count
 )
 
+// gtypes maps every type to the Go type of its values.
+var gtypes = map[ytype]reflect.Type{
+	Integer:   reflect.TypeOf(int(0)),
+	Integer8:  reflect.TypeOf(int8(0)),
+	Integer16: reflect.TypeOf(int16(0)),
+	Integer32: reflect.TypeOf(int32(0)),
+	Integer64: reflect.TypeOf(int64(0)),
+	Float32:   reflect.TypeOf(float32(0)),
+	Float64:   reflect.TypeOf(float64(0)),
+	String:    reflect.TypeOf(""),
+	Bool:      reflect.TypeOf(false),
+	Count:     reflect.TypeOf(int(0)),
+}
+
+// checkDefault checks that the default value v has the Go type of the type y.
+// If repeatable is true, v must be a slice of such values and it is returned as a slice of any.
+func checkDefault(y ytype, repeatable bool, v any) (any, error) {
+	t := gtypes[y]
+
+	if !repeatable {
+		if reflect.TypeOf(v) != t {
+			return nil, fmt.Errorf("default value '%v' of type %T, expected %s", v, v, t)
+		}
+		return v, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem() != t {
+		return nil, fmt.Errorf("default value '%v' of type %T, expected []%s", v, v, t)
+	}
+
+	vs := make([]any, rv.Len())
+	for i := range vs {
+		vs[i] = rv.Index(i).Interface()
+	}
+
+	return vs, nil
+}
+
 type vfunc func(v any) (any, error)
 
 var vfuncs map[ytype]vfunc = map[ytype]vfunc{
@@ -48,6 +101,7 @@ var vfuncs map[ytype]vfunc = map[ytype]vfunc{
 	Float64:   func(v any) (any, error) { return validateFloat[float64](v) },
 	String:    func(v any) (any, error) { return v.(string), nil },
 	Bool:      func(v any) (any, error) { return validateBool(v) },
+	Count:     func(v any) (any, error) { return validateCount(v) },
 }
 
 func validateInteger[T constraints.Integer](v any) (T, error) {
@@ -74,3 +128,19 @@ func validateBool(v any) (bool, error) {
 	}
 	return strconv.ParseBool(v.(string))
 }
+
+func validateCount(v any) (int, error) {
+	n, ok := v.(int)
+	if !ok {
+		var err error
+		if n, err = strconv.Atoi(v.(string)); err != nil {
+			return 0, err
+		}
+	}
+
+	if n < 0 {
+		return 0, fmt.Errorf("count can not be negative: %d", n)
+	}
+
+	return n, nil
+}
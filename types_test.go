@@ -105,3 +105,33 @@ func TestValidateFloat(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		v      any
+		want   int
+		hasErr bool
+	}{
+		{"counted occurrences", 3, 3, false},
+		{"valid string", "2", 2, false},
+		{"invalid string", "two", 0, true},
+		{"negative string", "-3", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateCount(tt.v)
+			if (err != nil) != tt.hasErr {
+				t.Errorf(
+					"validateCount('%v')=%v error=%v, wantErr %v",
+					tt.v, got, err, tt.hasErr,
+				)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("validateCount('%v')=%v, want %v", tt.v, got, tt.want)
+			}
+		})
+	}
+}